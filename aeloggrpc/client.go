@@ -0,0 +1,185 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aeloggrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/phst/aelog"
+)
+
+// ClientOptions contains options for [UnaryClientInterceptor] and
+// [StreamClientInterceptor].
+type ClientOptions struct {
+	// Level of the records for successful calls.  Calls that fail with a
+	// code that indicates a server-side problem (for example,
+	// [codes.Unavailable] or [codes.Internal]) are logged at
+	// [aelog.LevelWarn] or Level, whichever is higher.  If nil, use
+	// [aelog.LevelInfo].
+	Level slog.Leveler
+
+	// Logger for the records.  If nil, use [slog.Default].
+	Logger *slog.Logger
+
+	// If set, propagate the trace associated with the call context (see
+	// [aelog.ContextWithTrace]) to the server using the traceparent and
+	// X-Cloud-Trace-Context metadata, like [aelog.Transport.PropagateTrace]
+	// does for HTTP requests.  Calls whose outgoing metadata already
+	// contains traceparent or X-Cloud-Trace-Context are passed on
+	// unchanged.  Only enable this for calls to trusted servers, since the
+	// metadata reveals the trace ID.
+	PropagateTrace bool
+}
+
+// OutgoingCallKey is the key of the group attribute describing an outgoing
+// call.
+const OutgoingCallKey = "outgoingCall"
+
+// UnaryClientInterceptor returns a [grpc.UnaryClientInterceptor] that logs a
+// record for each outgoing unary call, containing the method, the status
+// code, and the latency.  If [ClientOptions.PropagateTrace] is set, it also
+// propagates the trace of the call context to the server.  Install it using
+// [grpc.WithUnaryInterceptor] or [grpc.WithChainUnaryInterceptor].  Passing
+// nil options has the same effect as passing a pointer to a zero struct.
+func UnaryClientInterceptor(opts *ClientOptions) grpc.UnaryClientInterceptor {
+	if opts == nil {
+		opts = new(ClientOptions)
+	}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(opts.outgoingContext(ctx), method, req, reply, cc, callOpts...)
+		opts.log(ctx, method, err, time.Since(start))
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a [grpc.StreamClientInterceptor] that does
+// for streaming calls what [UnaryClientInterceptor] does for unary calls.
+// The record is logged once the stream ends, that is, once receiving a
+// message fails or, for calls where the server doesn’t stream, once the
+// response has been received.  The latency covers the entire stream.  Install
+// it using [grpc.WithStreamInterceptor] or [grpc.WithChainStreamInterceptor].
+func StreamClientInterceptor(opts *ClientOptions) grpc.StreamClientInterceptor {
+	if opts == nil {
+		opts = new(ClientOptions)
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(opts.outgoingContext(ctx), desc, cc, method, callOpts...)
+		if err != nil {
+			opts.log(ctx, method, err, time.Since(start))
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, opts: opts, ctx: ctx, method: method, serverStreams: desc.ServerStreams, start: start}, nil
+	}
+}
+
+// clientStream wraps a client stream to log a record once the stream ends.
+type clientStream struct {
+	grpc.ClientStream
+	opts          *ClientOptions
+	ctx           context.Context
+	method        string
+	serverStreams bool
+	start         time.Time
+	once          sync.Once
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	// If the server doesn’t stream, the stream is complete after the
+	// first message; the client doesn’t have to wait for io.EOF.
+	if err != nil || !s.serverStreams {
+		s.once.Do(func() {
+			logErr := err
+			// io.EOF signals successful completion.
+			if errors.Is(logErr, io.EOF) {
+				logErr = nil
+			}
+			s.opts.log(s.ctx, s.method, logErr, time.Since(s.start))
+		})
+	}
+	return err
+}
+
+func (o *ClientOptions) log(ctx context.Context, method string, err error, latency time.Duration) {
+	level := aelog.LevelInfo
+	if o.Level != nil {
+		level = o.Level.Level()
+	}
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.String("latency", aelog.FormatDuration(latency)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if serverCodes[code] {
+		level = max(level, aelog.LevelWarn)
+	}
+	logger := o.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.LogAttrs(ctx, level, "outgoing call", slog.Attr{Key: OutgoingCallKey, Value: slog.GroupValue(attrs...)})
+}
+
+// serverCodes contains the status codes that indicate a server-side problem.
+// They correspond roughly to HTTP status codes 5xx.
+var serverCodes = map[codes.Code]bool{
+	codes.Unknown:          true,
+	codes.DeadlineExceeded: true,
+	codes.Unimplemented:    true,
+	codes.Internal:         true,
+	codes.Unavailable:      true,
+	codes.DataLoss:         true,
+}
+
+// outgoingContext returns a derived context whose outgoing metadata contains
+// the trace associated with ctx if o.PropagateTrace is set, there is such a
+// trace, and the outgoing metadata doesn’t specify a trace yet.
+func (o *ClientOptions) outgoingContext(ctx context.Context) context.Context {
+	if !o.PropagateTrace {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && (len(md.Get("traceparent")) > 0 || len(md.Get("x-cloud-trace-context")) > 0) {
+		return ctx
+	}
+	h := make(http.Header)
+	if !aelog.SetTraceHeader(ctx, h) {
+		return ctx
+	}
+	var kv []string
+	for k, vs := range h {
+		for _, v := range vs {
+			kv = append(kv, strings.ToLower(k), v)
+		}
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
@@ -0,0 +1,170 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aeloggrpc_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/aeloggrpc"
+)
+
+func TestClientInterceptors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	opts := &aeloggrpc.ClientOptions{Logger: slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{Level: aelog.LevelDebug}, &aelog.Options{ProjectID: "test"})), Level: aelog.LevelDebug, PropagateTrace: true}
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331", Sampled: true})
+
+	var md []metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		m, _ := metadata.FromOutgoingContext(ctx)
+		md = append(md, m)
+		return status.Error(codes.Unavailable, "down")
+	}
+	if err := aeloggrpc.UnaryClientInterceptor(opts)(ctx, "/pkg.Service/Unary", nil, nil, nil, invoker); status.Code(err) != codes.Unavailable {
+		t.Errorf("unary call: got error %v, want code Unavailable", err)
+	}
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		m, _ := metadata.FromOutgoingContext(ctx)
+		md = append(md, m)
+		return fakeClientStream{}, nil
+	}
+	cs, err := aeloggrpc.StreamClientInterceptor(opts)(ctx, new(grpc.StreamDesc), nil, "/pkg.Service/Stream", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := cs.RecvMsg(nil); err != io.EOF {
+			t.Errorf("RecvMsg: got error %v, want io.EOF", err)
+		}
+	}
+
+	wantMD := metadata.Pairs(
+		"x-cloud-trace-context", "0af7651916cd43dd8448eb211c80319c/13235353014750950193;o=1",
+		"traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	)
+	if diff := cmp.Diff(md, []metadata.MD{wantMD, wantMD}); diff != "" {
+		t.Error("metadata: -got +want", diff)
+	}
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{
+			"severity": "WARNING",
+			"message":  "outgoing call",
+			"outgoingCall": map[string]any{
+				"method": "/pkg.Service/Unary",
+				"code":   "Unavailable",
+				"error":  "rpc error: code = Unavailable desc = down",
+			},
+		},
+		{
+			"severity": "DEBUG",
+			"message":  "outgoing call",
+			"outgoingCall": map[string]any{
+				"method": "/pkg.Service/Stream",
+				"code":   "OK",
+			},
+		},
+	}
+	for _, m := range want {
+		m["logging.googleapis.com/trace"] = "projects/test/traces/0af7651916cd43dd8448eb211c80319c"
+		m["logging.googleapis.com/spanId"] = "b7ad6b7169203331"
+		m["logging.googleapis.com/trace_sampled"] = true
+	}
+	ignore := cmpopts.IgnoreMapEntries(func(k string, _ any) bool { return k == aelog.TimeKey || k == "latency" })
+	if diff := cmp.Diff(got, want, ignore); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestClientOptions_PropagateTrace_existing(t *testing.T) {
+	opts := &aeloggrpc.ClientOptions{Logger: slog.New(aelog.NewHandler(io.Discard, nil, nil)), PropagateTrace: true}
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"})
+	ctx = metadata.AppendToOutgoingContext(ctx, "traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	var md metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := aeloggrpc.UnaryClientInterceptor(opts)(ctx, "/pkg.Service/Unary", nil, nil, nil, invoker); err != nil {
+		t.Error(err)
+	}
+	want := metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if diff := cmp.Diff(md, want); diff != "" {
+		t.Error("metadata: -got +want", diff)
+	}
+}
+
+type fakeClientStream struct{ grpc.ClientStream }
+
+func (fakeClientStream) RecvMsg(any) error { return io.EOF }
+
+func TestStreamClientInterceptor_completion(t *testing.T) {
+	buf := new(bytes.Buffer)
+	opts := &aeloggrpc.ClientOptions{Logger: slog.New(aelog.NewHandler(buf, nil, nil))}
+	var md []metadata.MD
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		m, _ := metadata.FromOutgoingContext(ctx)
+		md = append(md, m)
+		return okClientStream{}, nil
+	}
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"})
+	for _, desc := range []*grpc.StreamDesc{
+		{ClientStreams: true},
+		{ClientStreams: true, ServerStreams: true},
+	} {
+		cs, err := aeloggrpc.StreamClientInterceptor(opts)(ctx, desc, nil, "/pkg.Service/Stream", streamer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 2 {
+			if err := cs.RecvMsg(nil); err != nil {
+				t.Errorf("RecvMsg: %v", err)
+			}
+		}
+	}
+
+	// Without PropagateTrace, the interceptor doesn’t add metadata.
+	if diff := cmp.Diff(md, []metadata.MD{nil, nil}); diff != "" {
+		t.Error("metadata: -got +want", diff)
+	}
+	// Only the stream without server streaming is complete.
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity":     "INFO",
+		"message":      "outgoing call",
+		"outgoingCall": map[string]any{"method": "/pkg.Service/Stream", "code": "OK"},
+	}}
+	ignore := cmpopts.IgnoreMapEntries(func(k string, _ any) bool { return k == aelog.TimeKey || k == "latency" })
+	if diff := cmp.Diff(got, want, ignore); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+type okClientStream struct{ grpc.ClientStream }
+
+func (okClientStream) RecvMsg(any) error { return nil }
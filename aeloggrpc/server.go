@@ -0,0 +1,88 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aeloggrpc connects [aelog] with [gRPC] servers and clients.
+//
+// [gRPC]: https://grpc.io/
+package aeloggrpc
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/phst/aelog"
+)
+
+// UnaryServerInterceptor is a [grpc.UnaryServerInterceptor] that does for
+// unary RPCs what [aelog.Middleware] does for HTTP requests: it associates
+// the context passed to the handler with a description of the call and with
+// the trace from the incoming metadata, so that an [aelog.Handler] correlates
+// log records with the call.  Install it using [grpc.UnaryInterceptor] or
+// [grpc.ChainUnaryInterceptor].
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(serverContext(ctx, info.FullMethod), req)
+}
+
+// StreamServerInterceptor is a [grpc.StreamServerInterceptor] that does for
+// streaming RPCs what [UnaryServerInterceptor] does for unary RPCs.  The
+// handler receives a wrapped server stream whose Context method returns the
+// associated context, so that all records logged during the stream
+// correlate with the call.  Install it using [grpc.StreamInterceptor] or
+// [grpc.ChainStreamInterceptor].
+func StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &serverStream{ss, serverContext(ss.Context(), info.FullMethod)})
+}
+
+// serverContext returns a derived context for an incoming call to the given
+// method.
+func serverContext(ctx context.Context, method string) context.Context {
+	h := make(http.Header)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, vs := range md {
+		for _, v := range vs {
+			h.Add(k, v)
+		}
+	}
+	// gRPC calls are HTTP/2 POST requests to the method path, see
+	// https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md.
+	attrs := []slog.Attr{
+		slog.String("requestMethod", http.MethodPost),
+		slog.String("requestUrl", method),
+		slog.String("protocol", "HTTP/2"),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		attrs = append(attrs, slog.String("remoteIp", p.Addr.String()))
+	}
+	if ua := h.Get("User-Agent"); ua != "" {
+		attrs = append(attrs, slog.String("userAgent", ua))
+	}
+	ctx = aelog.ContextWithHTTPRequest(ctx, slog.GroupValue(attrs...))
+	if t, ok := aelog.TraceFromHeader(h); ok {
+		ctx = aelog.ContextWithTrace(ctx, t)
+	}
+	return ctx
+}
+
+// serverStream wraps a server stream to replace its context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,108 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aeloggrpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/aeloggrpc"
+)
+
+func TestServerInterceptors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{ProjectID: "test"}))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"user-agent", "grpc-go/1.0",
+		"traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}})
+
+	unary := func(ctx context.Context, req any) (any, error) {
+		log.InfoContext(ctx, "unary")
+		return req, nil
+	}
+	if _, err := aeloggrpc.UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Unary"}, unary); err != nil {
+		t.Error(err)
+	}
+	stream := func(_ any, ss grpc.ServerStream) error {
+		log.InfoContext(ss.Context(), "stream")
+		return nil
+	}
+	if err := aeloggrpc.StreamServerInterceptor(nil, fakeStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/pkg.Service/Stream"}, stream); err != nil {
+		t.Error(err)
+	}
+
+	got := parseRecords(t, buf)
+	record := func(msg, method string) map[string]any {
+		return map[string]any{
+			"severity": "INFO",
+			"message":  msg,
+			"httpRequest": map[string]any{
+				"requestMethod": "POST",
+				"requestUrl":    method,
+				"protocol":      "HTTP/2",
+				"remoteIp":      "192.0.2.1:1234",
+				"userAgent":     "grpc-go/1.0",
+			},
+			"logging.googleapis.com/trace":         "projects/test/traces/0af7651916cd43dd8448eb211c80319c",
+			"logging.googleapis.com/spanId":        "b7ad6b7169203331",
+			"logging.googleapis.com/trace_sampled": true,
+		}
+	}
+	want := []map[string]any{
+		record("unary", "/pkg.Service/Unary"),
+		record("stream", "/pkg.Service/Stream"),
+	}
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreMapEntries(func(k string, _ any) bool { return k == aelog.TimeKey })); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeStream) Context() context.Context { return s.ctx }
+
+func parseRecords(t *testing.T, r io.Reader) (recs []map[string]any) {
+	t.Helper()
+	dec := json.NewDecoder(r)
+	for {
+		var m map[string]any
+		err := dec.Decode(&m)
+		if err == io.EOF {
+			return recs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, m)
+	}
+}
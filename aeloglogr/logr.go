@@ -0,0 +1,139 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aeloglogr provides a [logr.LogSink] that logs through [slog], so
+// that code bases using [logr], such as Kubernetes controllers built with
+// controller-runtime, can write structured Cloud Logging entries with an
+// [aelog.Handler].  The sink passes a fixed context to the handler, so that
+// the handler correlates the records with the trace of that context.
+package aeloglogr
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/phst/aelog"
+)
+
+// NameKey is the key of the attribute that contains the name of the logger as
+// set by [logr.Logger.WithName].  Multiple names are joined with slashes.
+const NameKey = "logger"
+
+// NewLogger returns a [logr.Logger] that logs to h.  The logger passes ctx to
+// h for each record, for example the context of an incoming request, so
+// that an [aelog.Handler] correlates the records with the trace of the
+// request.  If ctx is nil, the logger uses [context.Background].
+//
+// Verbosity levels map to slog levels by negation, as for
+// [logr.FromSlogHandler]: V(0) logs at [aelog.LevelInfo], V(4) at
+// [aelog.LevelDebug].  Errors are logged at [aelog.LevelError] with the
+// error in an [aelog.Error] attribute.
+func NewLogger(ctx context.Context, h slog.Handler) logr.Logger {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return logr.New(&sink{h: h, ctx: ctx})
+}
+
+// FromContext returns a [logr.Logger] that logs to the handler of the logger
+// returned by [aelog.FromContext] with the given context.
+func FromContext(ctx context.Context) logr.Logger {
+	return NewLogger(ctx, aelog.FromContext(ctx).Handler())
+}
+
+// sink implements logr.LogSink and logr.CallDepthLogSink.
+type sink struct {
+	h     slog.Handler
+	ctx   context.Context
+	name  string
+	depth int
+}
+
+// Init implements [logr.LogSink.Init].
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+}
+
+// Enabled implements [logr.LogSink.Enabled].
+func (s *sink) Enabled(level int) bool {
+	return s.h.Enabled(s.ctx, slog.Level(-level))
+}
+
+// Info implements [logr.LogSink.Info].
+func (s *sink) Info(level int, msg string, keysAndValues ...any) {
+	s.log(slog.Level(-level), msg, nil, keysAndValues)
+}
+
+// Error implements [logr.LogSink.Error].
+func (s *sink) Error(err error, msg string, keysAndValues ...any) {
+	s.log(aelog.LevelError, msg, err, keysAndValues)
+}
+
+// log must be called directly from Info or Error.
+func (s *sink) log(level slog.Level, msg string, err error, keysAndValues []any) {
+	if !s.h.Enabled(s.ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// Skip runtime.Callers, log, Info or Error, and the logr.Logger
+	// methods as reported by Init and WithCallDepth.
+	runtime.Callers(3+s.depth, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	if s.name != "" {
+		r.AddAttrs(slog.String(NameKey, s.name))
+	}
+	if err != nil {
+		r.AddAttrs(aelog.Error(err))
+	}
+	r.Add(keysAndValues...)
+	// Like slog.Logger, ignore errors from the handler.
+	_ = s.h.Handle(s.ctx, r)
+}
+
+// WithValues implements [logr.LogSink.WithValues].
+func (s *sink) WithValues(keysAndValues ...any) logr.LogSink {
+	// Let slog.Record convert the key-value pairs to attributes.
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(keysAndValues...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	c := *s
+	c.h = s.h.WithAttrs(attrs)
+	return &c
+}
+
+// WithName implements [logr.LogSink.WithName].
+func (s *sink) WithName(name string) logr.LogSink {
+	c := *s
+	if c.name == "" {
+		c.name = name
+	} else {
+		c.name += "/" + name
+	}
+	return &c
+}
+
+// WithCallDepth implements [logr.CallDepthLogSink.WithCallDepth].
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	c := *s
+	c.depth += depth
+	return &c
+}
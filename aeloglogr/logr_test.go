@@ -0,0 +1,91 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aeloglogr_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/aeloglogr"
+	"github.com/phst/aelog/aelogtest"
+)
+
+func TestNewLogger(t *testing.T) {
+	h := aelogtest.NewHandler(&slog.HandlerOptions{AddSource: true, Level: aelog.LevelDebug}, nil)
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "abc", SpanID: "123"})
+	log := aeloglogr.NewLogger(ctx, h).WithName("controller").WithValues("a", 1)
+	log.Info("info", "b", "two")
+	log.WithName("reconciler").V(4).Info("debug")
+	log.V(5).Info("hidden")
+	log.Error(errors.New("failed"), "error")
+
+	opts := cmp.Options{
+		cmpopts.IgnoreFields(aelogtest.Entry{}, "Time", "SourceLocation", "JSON"),
+		cmpopts.EquateEmpty(),
+	}
+	trace := "projects/" + aelogtest.ProjectID + "/traces/abc"
+	want := []aelogtest.Entry{
+		{
+			Severity: "INFO",
+			Message:  "info",
+			Trace:    trace,
+			SpanID:   "123",
+			Attrs:    map[string]any{"logger": "controller", "a": 1.0, "b": "two"},
+		},
+		{
+			Severity: "DEBUG",
+			Message:  "debug",
+			Trace:    trace,
+			SpanID:   "123",
+			Attrs:    map[string]any{"logger": "controller/reconciler", "a": 1.0},
+		},
+		{
+			Severity: "ERROR",
+			Message:  "error",
+			Trace:    trace,
+			SpanID:   "123",
+			Attrs: map[string]any{
+				"logger": "controller",
+				"a":      1.0,
+				"error":  map[string]any{"message": "failed", "type": "*errors.errorString"},
+			},
+		},
+	}
+	got := h.Entries()
+	if diff := cmp.Diff(got, want, opts); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	for _, e := range got {
+		if e.SourceLocation == nil || filepath.Base(e.SourceLocation.File) != "logr_test.go" {
+			t.Errorf("entry %q has wrong source location %+v", e.Message, e.SourceLocation)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	h := aelogtest.NewHandler(nil, nil)
+	ctx := aelog.NewContext(context.Background(), slog.New(h))
+	aeloglogr.FromContext(ctx).Info("info")
+	if got, want := h.Messages(), []string{"info"}; !cmp.Equal(got, want) {
+		t.Errorf("got messages %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelogotel
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/phst/aelog"
+)
+
+// NewLogHandler returns a new [LogHandler] that emits records using a logger
+// obtained from p.  Passing nil options has the same effect as passing a
+// pointer to a zero struct.
+func NewLogHandler(p log.LoggerProvider, opts *LogOptions) *LogHandler {
+	if opts == nil {
+		opts = new(LogOptions)
+	}
+	name := opts.Name
+	if name == "" {
+		name = "github.com/phst/aelog/aelogotel"
+	}
+	level := opts.Level
+	if level == nil {
+		level = aelog.LevelInfo
+	}
+	return &LogHandler{logger: p.Logger(name), level: level, addSource: opts.AddSource}
+}
+
+// LogHandler is an [slog.Handler] that converts records to OpenTelemetry log
+// records, for organizations that send logs to an OpenTelemetry collector
+// instead of directly to Cloud Logging.  The logger provider is responsible
+// for exporting the records, typically a provider from
+// [go.opentelemetry.io/otel/sdk/log] with an OTLP exporter such as
+// [go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc]:
+//
+//	exp, err := otlploggrpc.New(ctx)
+//	// …
+//	p := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(exp)))
+//	defer p.Shutdown(ctx)
+//	log := slog.New(aelogotel.NewLogHandler(p, nil))
+//
+// Levels map to OpenTelemetry severities such that [aelog.LevelInfo] becomes
+// INFO, [aelog.LevelWarn] becomes WARN, and so on; [aelog.LevelCritical] and
+// above become FATAL.  Groups become map values.  If the context doesn’t
+// contain an OpenTelemetry span but a trace set by [aelog.Middleware] or
+// [aelog.ContextWithTrace], the handler passes that trace on to the logger,
+// so that records are still correlated with the request.
+//
+// Use [NewLogHandler] to create LogHandler objects.
+type LogHandler struct {
+	logger    log.Logger
+	level     slog.Leveler
+	addSource bool
+	goas      []groupOrAttrs
+}
+
+// LogOptions contains options for a [LogHandler].
+type LogOptions struct {
+	// Name of the instrumentation scope passed to
+	// [log.LoggerProvider.Logger].  If empty, use
+	// “github.com/phst/aelog/aelogotel”.
+	Name string
+
+	// Minimum level of records to emit.  If nil, use [aelog.LevelInfo].
+	Level slog.Leveler
+
+	// Whether to add the source location as the code.filepath,
+	// code.lineno, and code.function attributes.
+	AddSource bool
+}
+
+// groupOrAttrs is either a group name or a list of attributes added by
+// WithGroup or WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// Enabled implements [slog.Handler.Enabled].
+func (h *LogHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if l < h.level.Level() {
+		return false
+	}
+	var p log.EnabledParameters
+	p.SetSeverity(severity(l))
+	return h.logger.Enabled(ctx, p)
+}
+
+// Handle implements [slog.Handler.Handle].
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var rec log.Record
+	rec.SetTimestamp(r.Time)
+	rec.SetObservedTimestamp(time.Now())
+	rec.SetSeverity(severity(r.Level))
+	rec.SetSeverityText(r.Level.String())
+	rec.SetBody(log.StringValue(r.Message))
+	if h.addSource && r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		rec.AddAttributes(
+			log.String(string(semconv.CodeFilepathKey), f.File),
+			log.Int(string(semconv.CodeLineNumberKey), f.Line),
+			log.String(string(semconv.CodeFunctionKey), f.Function),
+		)
+	}
+	// Nest the record attributes in the groups from the innermost group
+	// outwards.
+	kvs := make([]log.KeyValue, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		kvs = appendAttr(kvs, a)
+		return true
+	})
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group == "" {
+			var pre []log.KeyValue
+			for _, a := range goa.attrs {
+				pre = appendAttr(pre, a)
+			}
+			kvs = append(pre, kvs...)
+		} else if len(kvs) > 0 {
+			kvs = []log.KeyValue{log.Map(goa.group, kvs...)}
+		}
+	}
+	rec.AddAttributes(kvs...)
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if sc, ok := spanContext(ctx); ok {
+			ctx = trace.ContextWithSpanContext(ctx, sc)
+		}
+	}
+	h.logger.Emit(ctx, rec)
+	return nil
+}
+
+// WithAttrs implements [slog.Handler.WithAttrs].
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup implements [slog.Handler.WithGroup].
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *LogHandler) with(goa groupOrAttrs) *LogHandler {
+	r := *h
+	r.goas = append(slices.Clip(h.goas), goa)
+	return &r
+}
+
+// severity returns the OpenTelemetry severity for a level.  The severity
+// numbers are spaced such that slog levels map to them by adding an offset;
+// see
+// https://opentelemetry.io/docs/specs/otel/logs/data-model-appendix/#appendix-b-severitynumber-example-mappings.
+func severity(l slog.Level) log.Severity {
+	return log.Severity(min(max(int(l)+int(log.SeverityInfo), int(log.SeverityTrace1)), int(log.SeverityFatal4)))
+}
+
+// appendAttr converts an attribute and appends it to kvs.  It omits empty
+// attributes and inlines groups with an empty key, as required by
+// [slog.Handler].
+func appendAttr(kvs []log.KeyValue, a slog.Attr) []log.KeyValue {
+	v := a.Value.Resolve()
+	if a.Key == "" && v.Kind() == slog.KindAny && v.Any() == nil {
+		return kvs
+	}
+	if v.Kind() == slog.KindGroup {
+		var members []log.KeyValue
+		for _, b := range v.Group() {
+			members = appendAttr(members, b)
+		}
+		if len(members) == 0 {
+			return kvs
+		}
+		if a.Key == "" {
+			return append(kvs, members...)
+		}
+		return append(kvs, log.Map(a.Key, members...))
+	}
+	return append(kvs, log.KeyValue{Key: a.Key, Value: value(v)})
+}
+
+// value converts a resolved non-group value.
+func value(v slog.Value) log.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		return log.StringValue(v.String())
+	case slog.KindInt64:
+		return log.Int64Value(v.Int64())
+	case slog.KindUint64:
+		if u := v.Uint64(); u <= math.MaxInt64 {
+			return log.Int64Value(int64(u))
+		}
+		return log.StringValue(strconv.FormatUint(v.Uint64(), 10))
+	case slog.KindFloat64:
+		return log.Float64Value(v.Float64())
+	case slog.KindBool:
+		return log.BoolValue(v.Bool())
+	case slog.KindDuration:
+		return log.Int64Value(v.Duration().Nanoseconds())
+	case slog.KindTime:
+		return log.StringValue(v.Time().Format(time.RFC3339Nano))
+	}
+	switch x := v.Any().(type) {
+	case nil:
+		return log.Value{}
+	case []byte:
+		return log.BytesValue(x)
+	case error:
+		return log.StringValue(x.Error())
+	default:
+		return log.StringValue(fmt.Sprint(x))
+	}
+}
+
+// spanContext converts the trace set by aelog.ContextWithTrace to an
+// OpenTelemetry span context.  X-Cloud-Trace-Context headers specify span IDs
+// as decimal numbers, W3C traceparent headers as hexadecimal numbers with 16
+// digits.  Span IDs that could be either are treated as hexadecimal.
+func spanContext(ctx context.Context) (trace.SpanContext, bool) {
+	t, ok := aelog.TraceFromContext(ctx)
+	if !ok {
+		return trace.SpanContext{}, false
+	}
+	traceID, err := trace.TraceIDFromHex(t.ID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	var spanID trace.SpanID
+	if b, err := hex.DecodeString(t.SpanID); err == nil && len(b) == len(spanID) && !t.DecimalSpanID {
+		copy(spanID[:], b)
+	} else if n, err := strconv.ParseUint(t.SpanID, 10, 64); err == nil {
+		for i := range spanID {
+			spanID[len(spanID)-1-i] = byte(n >> (8 * i))
+		}
+	}
+	var flags trace.TraceFlags
+	if t.Sampled {
+		flags = trace.FlagsSampled
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	}), true
+}
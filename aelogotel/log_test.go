@@ -0,0 +1,170 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelogotel_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"testing/slogtest"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/logtest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/aelogotel"
+)
+
+func TestLogHandler(t *testing.T) {
+	rec := logtest.NewRecorder()
+	h := aelogotel.NewLogHandler(rec, &aelogotel.LogOptions{Level: aelog.LevelDebug, AddSource: true})
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{
+		ID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "123",
+		Sampled: true,
+	})
+	logger := slog.New(h).With("a", 1).WithGroup("g")
+	logger.DebugContext(ctx, "debug", "b", "two", slog.Group("h", "c", true))
+	logger.Log(ctx, aelog.LevelEmergency, "emergency", "err", errors.New("boom"))
+	logger.Log(ctx, aelog.LevelDebug-8, "hidden")
+
+	type record struct {
+		Severity     log.Severity
+		SeverityText string
+		Body         string
+		Attrs        map[string]any
+		TraceID      string
+		SpanID       string
+		Sampled      bool
+	}
+	var got []record
+	var files []string
+	for _, s := range rec.Result() {
+		if s.Name != "github.com/phst/aelog/aelogotel" {
+			t.Errorf("got scope name %q", s.Name)
+		}
+		for _, r := range s.Records {
+			attrs := make(map[string]any)
+			r.WalkAttributes(func(kv log.KeyValue) bool {
+				attrs[kv.Key] = simplify(kv.Value)
+				return true
+			})
+			files = append(files, filepath.Base(attrs["code.filepath"].(string)))
+			delete(attrs, "code.filepath")
+			delete(attrs, "code.lineno")
+			delete(attrs, "code.function")
+			sc := trace.SpanContextFromContext(r.Context())
+			got = append(got, record{
+				Severity:     r.Severity(),
+				SeverityText: r.SeverityText(),
+				Body:         r.Body().AsString(),
+				Attrs:        attrs,
+				TraceID:      sc.TraceID().String(),
+				SpanID:       sc.SpanID().String(),
+				Sampled:      sc.IsSampled(),
+			})
+		}
+	}
+	want := []record{
+		{
+			Severity:     log.SeverityDebug,
+			SeverityText: "DEBUG",
+			Body:         "debug",
+			Attrs: map[string]any{
+				"a": int64(1),
+				"g": map[string]any{"b": "two", "h": map[string]any{"c": true}},
+			},
+			TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:  "000000000000007b",
+			Sampled: true,
+		},
+		{
+			Severity:     log.SeverityFatal4,
+			SeverityText: "ERROR+12",
+			Body:         "emergency",
+			Attrs:        map[string]any{"a": int64(1), "g": map[string]any{"err": "boom"}},
+			TraceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:       "000000000000007b",
+			Sampled:      true,
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	for _, f := range files {
+		if f != "log_test.go" {
+			t.Errorf("got source file %q, want log_test.go", f)
+		}
+	}
+}
+
+func TestLogHandler_generic(t *testing.T) {
+	var rec *logtest.Recorder
+	newHandler := func(*testing.T) slog.Handler {
+		rec = logtest.NewRecorder()
+		return aelogotel.NewLogHandler(rec, nil)
+	}
+	result := func(t *testing.T) map[string]any {
+		var rs []logtest.EmittedRecord
+		for _, s := range rec.Result() {
+			rs = append(rs, s.Records...)
+		}
+		if len(rs) != 1 {
+			t.Fatalf("got %d records, want one", len(rs))
+		}
+		r := rs[0]
+		m := map[string]any{
+			slog.LevelKey:   r.SeverityText(),
+			slog.MessageKey: r.Body().AsString(),
+		}
+		if ts := r.Timestamp(); !ts.IsZero() {
+			m[slog.TimeKey] = ts
+		}
+		r.WalkAttributes(func(kv log.KeyValue) bool {
+			m[kv.Key] = simplify(kv.Value)
+			return true
+		})
+		return m
+	}
+	slogtest.Run(t, newHandler, result)
+}
+
+// simplify converts an OpenTelemetry log value to a Go value for comparison.
+func simplify(v log.Value) any {
+	switch v.Kind() {
+	case log.KindString:
+		return v.AsString()
+	case log.KindInt64:
+		return v.AsInt64()
+	case log.KindFloat64:
+		return v.AsFloat64()
+	case log.KindBool:
+		return v.AsBool()
+	case log.KindBytes:
+		return v.AsBytes()
+	case log.KindMap:
+		m := make(map[string]any)
+		for _, kv := range v.AsMap() {
+			m[kv.Key] = simplify(kv.Value)
+		}
+		return m
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aelogotel connects [aelog] with [OpenTelemetry].
+//
+// [OpenTelemetry]: https://opentelemetry.io/
+package aelogotel
+
+import (
+	"log/slog"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/phst/aelog"
+)
+
+// ResourceAttrs returns attributes that describe the given OpenTelemetry
+// resource in the format expected by Cloud Logging and Error Reporting.  The
+// service name and version go into a group with the key
+// [aelog.ServiceContextKey], which replaces the service context of the
+// handler; all other resource attributes (for example, the cloud region)
+// become labels of the log entry, with their values converted to strings.
+// ResourceAttrs returns nil for a nil or empty resource.
+//
+// Use [WithResource] to add the attributes to all records of a handler.
+func ResourceAttrs(res *resource.Resource) []slog.Attr {
+	if res == nil {
+		return nil
+	}
+	var svc, labels []slog.Attr
+	iter := res.Iter()
+	for iter.Next() {
+		kv := iter.Attribute()
+		switch kv.Key {
+		case semconv.ServiceNameKey:
+			svc = append(svc, slog.String("service", kv.Value.Emit()))
+		case semconv.ServiceVersionKey:
+			svc = append(svc, slog.String("version", kv.Value.Emit()))
+		default:
+			labels = append(labels, slog.String(string(kv.Key), kv.Value.Emit()))
+		}
+	}
+	var attrs []slog.Attr
+	if len(svc) > 0 {
+		attrs = append(attrs, slog.Attr{Key: aelog.ServiceContextKey, Value: slog.GroupValue(svc...)})
+	}
+	if len(labels) > 0 {
+		attrs = append(attrs, slog.Attr{Key: aelog.LabelsKey, Value: slog.GroupValue(labels...)})
+	}
+	return attrs
+}
+
+// WithResource returns a handler that adds the attributes returned by
+// [ResourceAttrs] to all records.  Call it with the same resource that you
+// use for the OpenTelemetry tracer provider, so that logs and traces share the
+// same resource detection:
+//
+//	res, err := resource.New(ctx, resource.WithDetectors(gcp.NewDetector()))
+//	// …
+//	h := aelogotel.WithResource(aelog.NewHandler(os.Stderr, nil, nil), res)
+func WithResource(h slog.Handler, res *resource.Resource) slog.Handler {
+	attrs := ResourceAttrs(res)
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.WithAttrs(attrs)
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelogotel_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/aelogotel"
+)
+
+func TestWithResource(t *testing.T) {
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("app"),
+		semconv.ServiceVersion("v1"),
+		semconv.CloudRegion("europe-west1"),
+	)
+	buf := new(bytes.Buffer)
+	log := slog.New(aelogotel.WithResource(aelog.NewHandler(buf, nil, nil), res))
+	log.Info("info")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	delete(got, aelog.TimeKey)
+	want := map[string]any{
+		"severity":       "INFO",
+		"message":        "info",
+		"serviceContext": map[string]any{"service": "app", "version": "v1"},
+		"logging.googleapis.com/labels": map[string]any{
+			"cloud.region": "europe-west1",
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
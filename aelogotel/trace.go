@@ -0,0 +1,41 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelogotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/phst/aelog"
+)
+
+// TraceFromSpan returns the trace of the OpenTelemetry span in the given
+// context, for example a span started by an instrumented HTTP server.  It
+// returns false if the context contains no valid span context.  Use it as
+// [aelog.Options.TraceExtractor]:
+//
+//	h := aelog.NewHandler(os.Stderr, nil, &aelog.Options{TraceExtractor: aelogotel.TraceFromSpan})
+func TraceFromSpan(ctx context.Context) (aelog.Trace, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return aelog.Trace{}, false
+	}
+	return aelog.Trace{
+		ID:      sc.TraceID().String(),
+		SpanID:  sc.SpanID().String(),
+		Sampled: sc.IsSampled(),
+	}, true
+}
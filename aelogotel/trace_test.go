@@ -0,0 +1,75 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelogotel_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/aelogotel"
+)
+
+func TestTraceFromSpan(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{
+		ProjectID:      "test",
+		TraceExtractor: aelogotel.TraceFromSpan,
+	}))
+	// The span takes precedence over the trace set by the middleware.
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "abc", SpanID: "123"})
+	log.InfoContext(trace.ContextWithSpanContext(ctx, sc), "span")
+	log.InfoContext(ctx, "fallback")
+
+	var got []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		delete(rec, aelog.TimeKey)
+		got = append(got, rec)
+	}
+	want := []map[string]any{
+		{
+			"severity":                             "INFO",
+			"message":                              "span",
+			"logging.googleapis.com/trace":         "projects/test/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+			"logging.googleapis.com/trace_sampled": true,
+			"logging.googleapis.com/spanId":        "00f067aa0ba902b7",
+		},
+		{
+			"severity":                      "INFO",
+			"message":                       "fallback",
+			"logging.googleapis.com/trace":  "projects/test/traces/abc",
+			"logging.googleapis.com/spanId": "123",
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
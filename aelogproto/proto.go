@@ -0,0 +1,128 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aelogproto logs [protocol buffer] messages in structured form.
+// Package [github.com/phst/aelog] itself doesn’t depend on the protocol buffer
+// runtime.
+//
+// [protocol buffer]: https://protobuf.dev/
+package aelogproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Attr returns an attribute whose value is [Value] of msg.
+func Attr(key string, msg proto.Message) slog.Attr {
+	return slog.Any(key, Value(msg))
+}
+
+// Value returns a value that renders msg using its [canonical JSON mapping],
+// for example to log API requests and responses.  Unlike the String method of
+// generated messages, this results in structured log entries whose fields can
+// be queried.  Messages that map to JSON objects become group values, so that
+// [slog.HandlerOptions.ReplaceAttr] and [aelog.Options.RedactKeys] see their
+// fields; other messages such as [durationpb.Duration] become strings.  JSON
+// arrays become []any values that contain the JSON form of the elements.  Like
+// [aelog.Lazy], Value only renders msg when a handler encodes the record, so
+// logging large messages at a disabled level is cheap.  The message must not
+// change until then.  If rendering fails, the value is a string starting with
+// “!ERROR:”.
+//
+// [canonical JSON mapping]: https://protobuf.dev/programming-guides/json/
+// [aelog.Options.RedactKeys]: https://pkg.go.dev/github.com/phst/aelog#Options.RedactKeys
+// [aelog.Lazy]: https://pkg.go.dev/github.com/phst/aelog#Lazy
+// [durationpb.Duration]: https://pkg.go.dev/google.golang.org/protobuf/types/known/durationpb#Duration
+func Value(msg proto.Message) slog.Value {
+	return slog.AnyValue(message{msg})
+}
+
+type message struct{ msg proto.Message }
+
+// LogValue implements [slog.LogValuer].
+func (m message) LogValue() slog.Value {
+	b, err := protojson.Marshal(m.msg)
+	if err != nil {
+		return slog.StringValue(fmt.Sprintf("!ERROR:%v", err))
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	v, err := decode(d)
+	if err != nil {
+		return slog.StringValue(fmt.Sprintf("!ERROR:%v", err))
+	}
+	return v
+}
+
+// decode decodes the next JSON value from d.  Unlike decoding into a map, it
+// preserves the order of object members, which protojson emits in field
+// number order.
+func decode(d *json.Decoder) (slog.Value, error) {
+	t, err := d.Token()
+	if err != nil {
+		return slog.Value{}, err
+	}
+	switch t := t.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			var attrs []slog.Attr
+			for d.More() {
+				k, err := d.Token()
+				if err != nil {
+					return slog.Value{}, err
+				}
+				v, err := decode(d)
+				if err != nil {
+					return slog.Value{}, err
+				}
+				attrs = append(attrs, slog.Attr{Key: k.(string), Value: v})
+			}
+			_, err := d.Token() // '}'
+			return slog.GroupValue(attrs...), err
+		case '[':
+			// There are no list values, so keep the elements in
+			// their JSON form.
+			elems := []any{}
+			for d.More() {
+				var e any
+				if err := d.Decode(&e); err != nil {
+					return slog.Value{}, err
+				}
+				elems = append(elems, e)
+			}
+			_, err := d.Token() // ']'
+			return slog.AnyValue(elems), err
+		}
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return slog.Int64Value(n), nil
+		}
+		f, err := t.Float64()
+		return slog.Float64Value(f), err
+	case string:
+		return slog.StringValue(t), nil
+	case bool:
+		return slog.BoolValue(t), nil
+	case nil:
+		return slog.AnyValue(nil), nil
+	}
+	return slog.Value{}, fmt.Errorf("unexpected JSON token %v", t)
+}
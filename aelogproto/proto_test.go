@@ -0,0 +1,79 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelogproto_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/aelogproto"
+)
+
+func TestAttr(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{OmitTime: true, RedactKeys: []string{"req.package"}}))
+	req := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("a.proto"),
+		Package:    proto.String("secret"),
+		Dependency: []string{"b.proto", "c.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/a")},
+	}
+	log.Info("call", aelogproto.Attr("req", req), aelogproto.Attr("latency", durationpb.New(1500*time.Millisecond)))
+	log.Debug("hidden", aelogproto.Attr("req", req))
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"severity": "INFO",
+		"message":  "call",
+		"req": map[string]any{
+			"name":       "a.proto",
+			"package":    "[REDACTED]",
+			"dependency": []any{"b.proto", "c.proto"},
+			"options":    map[string]any{"goPackage": "example.com/a"},
+		},
+		"latency": "1.500s",
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestValue_order(t *testing.T) {
+	v := aelogproto.Value(&descriptorpb.FileDescriptorProto{
+		Syntax:  proto.String("proto3"),
+		Name:    proto.String("a.proto"),
+		Package: proto.String("p"),
+	}).Resolve()
+	var keys []string
+	for _, a := range v.Group() {
+		keys = append(keys, a.Key)
+	}
+	// Field number order.
+	if diff := cmp.Diff(keys, []string{"name", "package", "syntax"}); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
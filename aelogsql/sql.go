@@ -0,0 +1,376 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aelogsql wraps [database/sql] drivers so that they log queries
+// through [slog] using the context passed to the context-aware methods of
+// [sql.DB].  Together with an [aelog.Handler] and [aelog.Middleware], this
+// correlates each query with the incoming request that caused it.
+//
+// Use [WrapConnector] together with [sql.OpenDB] or register a driver wrapped
+// with [WrapDriver] under a new name using [sql.Register].
+package aelogsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/phst/aelog"
+)
+
+// Options contains options for [WrapConnector] and [WrapDriver].
+type Options struct {
+	// Logger for the query records.  If nil, use [slog.Default].
+	Logger *slog.Logger
+
+	// Level of the records for successful queries.  If nil, use
+	// [aelog.LevelDebug].
+	Level slog.Leveler
+
+	// If positive, log queries that take at least this long at
+	// [aelog.LevelWarn] or Level, whichever is higher.
+	SlowThreshold time.Duration
+}
+
+// QueryKey is the key of the group attribute that describes a query.  The
+// group contains the normalized query text, the number of arguments, the
+// number of rows affected or returned, the duration, and the error message if
+// the query failed.
+const QueryKey = "sql"
+
+// WrapConnector returns a connector that logs queries on connections returned
+// by the given connector.  Passing nil options has the same effect as passing
+// a pointer to a zero struct.
+func WrapConnector(c driver.Connector, opts *Options) driver.Connector {
+	l := newLogger(opts)
+	return &connector{c, &drv{c.Driver(), l}, l}
+}
+
+// WrapDriver returns a driver that logs queries on connections returned by
+// the given driver.  Passing nil options has the same effect as passing a
+// pointer to a zero struct.
+func WrapDriver(d driver.Driver, opts *Options) driver.Driver {
+	return &drv{d, newLogger(opts)}
+}
+
+type logger struct {
+	log   *slog.Logger
+	level slog.Level
+	slow  time.Duration
+}
+
+func newLogger(opts *Options) *logger {
+	if opts == nil {
+		opts = new(Options)
+	}
+	l := &logger{log: opts.Logger, level: aelog.LevelDebug, slow: opts.SlowThreshold}
+	if l.log == nil {
+		l.log = slog.Default()
+	}
+	if opts.Level != nil {
+		l.level = opts.Level.Level()
+	}
+	return l
+}
+
+// query logs a finished query.  rows is negative if unknown.
+func (l *logger) query(ctx context.Context, query string, args int, rows int64, d time.Duration, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		// database/sql will retry the query in a different way, which
+		// we'll log then.
+		return
+	}
+	level := l.level
+	if l.slow > 0 && d >= l.slow {
+		level = max(level, aelog.LevelWarn)
+	}
+	if err != nil {
+		level = max(level, aelog.LevelError)
+	}
+	if !l.log.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("query", normalize(query)),
+		slog.Int("args", args),
+	}
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", rows))
+	}
+	attrs = append(attrs, slog.String("duration", aelog.FormatDuration(d)))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.log.LogAttrs(ctx, level, "SQL query", slog.Attr{Key: QueryKey, Value: slog.GroupValue(attrs...)})
+}
+
+// normalize collapses all runs of whitespace in the query text to single
+// spaces.
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+type connector struct {
+	base driver.Connector
+	drv  *drv
+	l    *logger
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{cn, c.l}, nil
+}
+
+func (c *connector) Driver() driver.Driver { return c.drv }
+
+type drv struct {
+	base driver.Driver
+	l    *logger
+}
+
+func (d *drv) Open(name string) (driver.Conn, error) {
+	cn, err := d.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{cn, d.l}, nil
+}
+
+func (d *drv) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.base.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &connector{c, d, d.l}, nil
+	}
+	return &dsnConnector{name, d}, nil
+}
+
+// dsnConnector is a connector for drivers that don’t implement
+// [driver.DriverContext], like the one in [database/sql].
+type dsnConnector struct {
+	name string
+	drv  *drv
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.name) }
+func (c *dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+type conn struct {
+	base driver.Conn
+	l    *logger
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	var err error
+	if p, ok := c.base.(driver.ConnPrepareContext); ok {
+		s, err = p.PrepareContext(ctx, query)
+	} else {
+		s, err = c.base.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{s, query, c.l}, nil
+}
+
+func (c *conn) Close() error { return c.base.Close() }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	//lint:ignore SA1019 required by the driver.Conn interface
+	return c.base.Begin()
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.base.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	// Same restrictions as in database/sql.
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("aelogsql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("aelogsql: driver does not support read-only transactions")
+	}
+	//lint:ignore SA1019 fallback for old drivers
+	return c.base.Begin()
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.base.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	c.l.query(ctx, query, len(args), rowsAffected(res), time.Since(start), err)
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.base.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	r, err := q.QueryContext(ctx, query, args)
+	if err != nil {
+		c.l.query(ctx, query, len(args), -1, time.Since(start), err)
+		return nil, err
+	}
+	return &rows{Rows: r, ctx: ctx, query: query, args: len(args), start: start, l: c.l}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.base.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.base.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.base.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(v *driver.NamedValue) error {
+	if n, ok := c.base.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+type stmt struct {
+	base  driver.Stmt
+	query string
+	l     *logger
+}
+
+func (s *stmt) Close() error  { return s.base.Close() }
+func (s *stmt) NumInput() int { return s.base.NumInput() }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.execValues(context.Background(), args)
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.queryValues(context.Background(), args)
+}
+
+// execValues runs the statement using the context-free driver interface,
+// but logs it with the given context.
+func (s *stmt) execValues(ctx context.Context, args []driver.Value) (driver.Result, error) {
+	start := time.Now()
+	//lint:ignore SA1019 required by the driver.Stmt interface
+	res, err := s.base.Exec(args)
+	s.l.query(ctx, s.query, len(args), rowsAffected(res), time.Since(start), err)
+	return res, err
+}
+
+// queryValues runs the statement using the context-free driver interface,
+// but logs it with the given context.
+func (s *stmt) queryValues(ctx context.Context, args []driver.Value) (driver.Rows, error) {
+	return s.queryContext(ctx, len(args), func() (driver.Rows, error) {
+		//lint:ignore SA1019 required by the driver.Stmt interface
+		return s.base.Query(args)
+	})
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := s.base.(driver.StmtExecContext)
+	if !ok {
+		vals, err := values(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.execValues(ctx, vals)
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, args)
+	s.l.query(ctx, s.query, len(args), rowsAffected(res), time.Since(start), err)
+	return res, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := s.base.(driver.StmtQueryContext)
+	if !ok {
+		vals, err := values(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.queryValues(ctx, vals)
+	}
+	return s.queryContext(ctx, len(args), func() (driver.Rows, error) { return q.QueryContext(ctx, args) })
+}
+
+func (s *stmt) queryContext(ctx context.Context, args int, query func() (driver.Rows, error)) (driver.Rows, error) {
+	start := time.Now()
+	r, err := query()
+	if err != nil {
+		s.l.query(ctx, s.query, args, -1, time.Since(start), err)
+		return nil, err
+	}
+	return &rows{Rows: r, ctx: ctx, query: s.query, args: args, start: start, l: s.l}, nil
+}
+
+func (s *stmt) CheckNamedValue(v *driver.NamedValue) error {
+	if n, ok := s.base.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+func values(args []driver.NamedValue) ([]driver.Value, error) {
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("aelogsql: driver does not support named parameters")
+		}
+		vals[i] = a.Value
+	}
+	return vals, nil
+}
+
+func rowsAffected(res driver.Result) int64 {
+	if res == nil {
+		return -1
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
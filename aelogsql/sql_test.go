@@ -0,0 +1,191 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelogsql_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/aelogsql"
+)
+
+func TestWrapConnector(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{Level: aelog.LevelDebug}, nil))
+	db := sql.OpenDB(aelogsql.WrapConnector(fakeConnector{}, &aelogsql.Options{Logger: log}))
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "UPDATE t\n  SET x = ?", 1); err != nil {
+		t.Error(err)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT x FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := db.ExecContext(ctx, "fail"); err == nil {
+		t.Error("ExecContext succeeded unexpectedly")
+	}
+
+	var got []map[string]any
+	dec := json.NewDecoder(buf)
+	for {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec)
+	}
+	want := []map[string]any{
+		{
+			"severity": "DEBUG",
+			"message":  "SQL query",
+			"sql":      map[string]any{"query": "UPDATE t SET x = ?", "args": 1.0, "rows": 2.0},
+		},
+		{
+			"severity": "DEBUG",
+			"message":  "SQL query",
+			"sql":      map[string]any{"query": "SELECT x FROM t", "args": 0.0, "rows": 3.0},
+		},
+		{
+			"severity": "ERROR",
+			"message":  "SQL query",
+			"sql":      map[string]any{"query": "fail", "args": 0.0, "error": "boom"},
+		},
+	}
+	ignore := cmpopts.IgnoreMapEntries(func(k string, _ any) bool { return k == aelog.TimeKey || k == "duration" })
+	if diff := cmp.Diff(got, want, ignore); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestWrapConnector_stmtWithoutContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{Level: aelog.LevelDebug}, &aelog.Options{ProjectID: "test"}))
+	db := sql.OpenDB(aelogsql.WrapConnector(fakeConnector{}, &aelogsql.Options{Logger: log}))
+	defer db.Close()
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "abc"})
+
+	stmt, err := db.PrepareContext(ctx, "UPDATE t SET x = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.ExecContext(ctx, 1); err != nil {
+		t.Error(err)
+	}
+	rows, err := stmt.QueryContext(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Close(); err != nil {
+		t.Error(err)
+	}
+
+	var got []map[string]any
+	dec := json.NewDecoder(buf)
+	for {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec)
+	}
+	want := []map[string]any{
+		{
+			"severity":                     "DEBUG",
+			"message":                      "SQL query",
+			"logging.googleapis.com/trace": "projects/test/traces/abc",
+			"sql":                          map[string]any{"query": "UPDATE t SET x = ?", "args": 1.0, "rows": 2.0},
+		},
+		{
+			"severity":                     "DEBUG",
+			"message":                      "SQL query",
+			"logging.googleapis.com/trace": "projects/test/traces/abc",
+			"sql":                          map[string]any{"query": "UPDATE t SET x = ?", "args": 1.0, "rows": 3.0},
+		},
+	}
+	ignore := cmpopts.IgnoreMapEntries(func(k string, _ any) bool { return k == aelog.TimeKey || k == "duration" })
+	if diff := cmp.Diff(got, want, ignore); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not implemented") }
+
+func (fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if query == "fail" {
+		return nil, errors.New("boom")
+	}
+	return driver.RowsAffected(2), nil
+}
+
+func (fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{n: 3}, nil
+}
+
+// fakeStmt is a statement that doesn’t support contexts.
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(2), nil }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return &fakeRows{n: 3}, nil }
+
+type fakeRows struct{ n int }
+
+func (*fakeRows) Columns() []string { return []string{"x"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		return io.EOF
+	}
+	r.n--
+	dest[0] = int64(r.n)
+	return nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelogtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"sync"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/phst/aelog"
+)
+
+// Run tests that wrapping an [aelog.Handler] with wrap results in a handler
+// that still fulfills both the [slog.Handler] contract, as checked by
+// [slogtest.Run], and the contract of [aelog.Handler]: levels map to the right
+// Cloud Logging severities, times are in UTC, special fields such as the
+// trace, labels, and source location are present, and [aelog.Validate] finds
+// no violations.  Use Run to check custom handlers that decorate
+// [aelog.Handler], for example to add attributes or filter records.  wrap
+// must pass all records at levels [aelog.LevelDebug] and above to the
+// handler it wraps.
+func Run(t *testing.T, wrap func(slog.Handler) slog.Handler) {
+	t.Run("slogtest", func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			buffers = make(map[*testing.T]*bytes.Buffer)
+		)
+		newHandler := func(t *testing.T) slog.Handler {
+			buf := new(bytes.Buffer)
+			mu.Lock()
+			buffers[t] = buf
+			mu.Unlock()
+			return wrap(newConformanceHandler(buf))
+		}
+		result := func(t *testing.T) map[string]any {
+			mu.Lock()
+			buf := buffers[t]
+			mu.Unlock()
+			var m map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+				t.Fatalf("invalid log entry %q: %s", buf, err)
+			}
+			// slogtest expects the standard keys, so translate them
+			// back.
+			for from, to := range map[string]string{
+				aelog.SeverityKey: slog.LevelKey,
+				aelog.MessageKey:  slog.MessageKey,
+			} {
+				if v, ok := m[from]; ok {
+					delete(m, from)
+					m[to] = v
+				}
+			}
+			return m
+		}
+		slogtest.Run(t, newHandler, result)
+	})
+
+	t.Run("severity", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		log := slog.New(wrap(newConformanceHandler(buf)))
+		severities := []string{"DEBUG", "INFO", "NOTICE", "WARNING", "ERROR", "CRITICAL", "ALERT", "EMERGENCY"}
+		for _, s := range severities {
+			l, err := aelog.ParseLevel(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			log.Log(context.Background(), l, s)
+		}
+		entries := decodeEntries(t, buf)
+		if len(entries) != len(severities) {
+			t.Fatalf("got %d entries, want %d", len(entries), len(severities))
+		}
+		for i, e := range entries {
+			if want := severities[i]; e.Severity != want {
+				t.Errorf("entry %d: got severity %q, want %q", i, e.Severity, want)
+			}
+		}
+	})
+
+	t.Run("time", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		h := wrap(newConformanceHandler(buf))
+		now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.FixedZone("test", 3600))
+		r := slog.NewRecord(now, aelog.LevelInfo, "info", 0)
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range decodeEntries(t, buf) {
+			if !e.Time.Equal(now) || e.Time.Location() != time.UTC {
+				t.Errorf("got time %v, want %v", e.Time, now.UTC())
+			}
+		}
+	})
+
+	t.Run("special", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		log := slog.New(wrap(newConformanceHandler(buf)))
+		ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{
+			ID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:  "00f067aa0ba902b7",
+			Sampled: true,
+		})
+		log.InfoContext(ctx, "info", aelog.Label("label", "value"))
+		entries := decodeEntries(t, buf)
+		if len(entries) != 1 {
+			t.Fatalf("got %d entries, want one", len(entries))
+		}
+		e := entries[0]
+		if e.Message != "info" {
+			t.Errorf("got message %q, want %q", e.Message, "info")
+		}
+		if want := "projects/" + ProjectID + "/traces/4bf92f3577b34da6a3ce929d0e0e4736"; e.Trace != want {
+			t.Errorf("got trace %q, want %q", e.Trace, want)
+		}
+		if want := "00f067aa0ba902b7"; e.SpanID != want {
+			t.Errorf("got span ID %q, want %q", e.SpanID, want)
+		}
+		if !e.TraceSampled {
+			t.Error("trace not sampled")
+		}
+		if got := e.Labels["label"]; got != "value" {
+			t.Errorf("got label %q, want %q", got, "value")
+		}
+		if e.SourceLocation == nil || e.SourceLocation.File == "" || e.SourceLocation.Line == 0 {
+			t.Errorf("missing source location: %+v", e.SourceLocation)
+		}
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		log := slog.New(wrap(newConformanceHandler(buf)))
+		log.Info("info", "a", 1, slog.Group("g", "b", 2), aelog.Label("label", "value"))
+		log.WithGroup("g").With("a", 1).Error("error", "err", io.EOF)
+		vs, err := aelog.Validate(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range vs {
+			t.Error(v)
+		}
+	})
+}
+
+// newConformanceHandler returns the handler that Run passes to the wrapper.
+func newConformanceHandler(w io.Writer) *aelog.Handler {
+	return aelog.NewHandler(w,
+		&slog.HandlerOptions{AddSource: true, Level: slog.Level(math.MinInt)},
+		&aelog.Options{ProjectID: ProjectID})
+}
+
+func decodeEntries(t *testing.T, r io.Reader) []Entry {
+	t.Helper()
+	entries, err := aelog.DecodeEntries(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
@@ -0,0 +1,164 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aelogtest provides an [slog.Handler] for tests that records the log
+// entries written by an [aelog.Handler] in memory.  Tests can then make
+// assertions about the logging behavior of the code under test without
+// parsing JSON themselves.
+//
+//	h := aelogtest.NewHandler(nil, nil)
+//	log := slog.New(h)
+//	log.Warn("disk almost full", "free", 1000)
+//	e, ok := h.Find("disk almost full")
+//	if !ok || e.Severity != "WARNING" {
+//		t.Error("missing warning")
+//	}
+package aelogtest
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/phst/aelog"
+)
+
+// ProjectID is the project ID that [NewHandler] uses unless the options
+// specify a different one.  Using a fixed project ID avoids querying the
+// metadata server and makes trace names predictable.
+const ProjectID = "test-project"
+
+// Handler is an [aelog.Handler] that records the entries it writes in memory.
+// Use [NewHandler] to create Handler objects.  Handlers derived from a
+// Handler using WithAttrs or WithGroup write to the same memory.  Handler
+// objects are safe for concurrent use.
+type Handler struct {
+	*aelog.Handler
+	rec *recorder
+}
+
+// NewHandler returns a new handler that records entries in memory.  The
+// options are the same as for [aelog.NewHandler], except that the handler
+// records all levels if opts or opts.Level is nil, and that it uses
+// [ProjectID] if extOpts or extOpts.ProjectID is empty.  Options that
+// configure writers, such as [aelog.Options.Routes], still work, but the
+// handler doesn’t record entries written to those writers.
+func NewHandler(opts *slog.HandlerOptions, extOpts *aelog.Options) *Handler {
+	var basic slog.HandlerOptions
+	if opts != nil {
+		basic = *opts
+	}
+	if basic.Level == nil {
+		basic.Level = slog.Level(math.MinInt)
+	}
+	var ext aelog.Options
+	if extOpts != nil {
+		ext = *extOpts
+	}
+	if ext.ProjectID == "" {
+		ext.ProjectID = ProjectID
+	}
+	rec := new(recorder)
+	return &Handler{aelog.NewHandler(rec, &basic, &ext), rec}
+}
+
+// Entries returns the entries recorded so far, in order.
+func (h *Handler) Entries() []Entry {
+	h.rec.mu.Lock()
+	defer h.rec.mu.Unlock()
+	return slices.Clone(h.rec.entries)
+}
+
+// Filter returns the recorded entries for which f returns true, in order.
+func (h *Handler) Filter(f func(Entry) bool) []Entry {
+	var r []Entry
+	for _, e := range h.Entries() {
+		if f(e) {
+			r = append(r, e)
+		}
+	}
+	return r
+}
+
+// Find returns the first recorded entry with the given message.
+func (h *Handler) Find(msg string) (Entry, bool) {
+	for _, e := range h.Entries() {
+		if e.Message == msg {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Messages returns the messages of the recorded entries, in order.
+func (h *Handler) Messages() []string {
+	var r []string
+	for _, e := range h.Entries() {
+		r = append(r, e.Message)
+	}
+	return r
+}
+
+// Reset removes all recorded entries.
+func (h *Handler) Reset() {
+	h.rec.mu.Lock()
+	defer h.rec.mu.Unlock()
+	h.rec.entries = nil
+	h.rec.err = nil
+}
+
+// Err returns an error if the handler wrote anything that it couldn’t
+// decode as a log entry.  This indicates a bug in package aelog.
+func (h *Handler) Err() error {
+	h.rec.mu.Lock()
+	defer h.rec.mu.Unlock()
+	return h.rec.err
+}
+
+// recorder is the writer for a Handler.  It decodes the JSON lines written to
+// it.
+type recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+	err     error
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for rest := b; len(rest) > 0; {
+		var line []byte
+		line, rest, _ = bytes.Cut(rest, []byte{'\n'})
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		e, err := aelog.ParseEntry(line)
+		if err != nil {
+			r.err = errors.Join(r.err, err)
+			return 0, err
+		}
+		r.entries = append(r.entries, e)
+	}
+	return len(b), nil
+}
+
+// Entry is a log entry recorded by a [Handler].
+type Entry = aelog.Entry
+
+// SourceLocation is the source location of a log entry.
+type SourceLocation = aelog.SourceLocation
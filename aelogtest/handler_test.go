@@ -0,0 +1,139 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelogtest_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/aelogtest"
+)
+
+func Example() {
+	h := aelogtest.NewHandler(nil, nil)
+	log := slog.New(h)
+	log.Warn("disk almost full", "free", 1000)
+	e, ok := h.Find("disk almost full")
+	fmt.Println(ok, e.Severity, e.Attrs["free"])
+	// Output:
+	// true WARNING 1000
+}
+
+func TestHandler(t *testing.T) {
+	h := aelogtest.NewHandler(&slog.HandlerOptions{AddSource: true}, &aelog.Options{
+		Labels: map[string]string{"env": "test"},
+	})
+	log := slog.New(h)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		log.With("a", 1).WithGroup("g").DebugContext(r.Context(), "debug", "b", "two")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "abc/123;o=1")
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+	log.ErrorContext(context.Background(), "error")
+
+	got := h.Entries()
+	want := []aelogtest.Entry{
+		{
+			Severity:     "DEBUG",
+			Message:      "debug",
+			Trace:        "projects/test-project/traces/abc",
+			SpanID:       "123",
+			TraceSampled: true,
+			Labels:       map[string]string{"env": "test"},
+			HTTPRequest: &aelog.HTTPRequest{
+				RequestMethod: "GET",
+				RequestURL:    "/path",
+				RemoteIP:      "192.0.2.1:1234",
+				Protocol:      "HTTP/1.1",
+			},
+			Attrs: map[string]any{"a": 1.0, "g": map[string]any{"b": "two"}},
+		},
+		{
+			Severity: "ERROR",
+			Message:  "error",
+			Labels:   map[string]string{"env": "test"},
+			Attrs:    map[string]any{},
+		},
+	}
+	opts := cmp.Options{
+		cmpopts.IgnoreFields(aelogtest.Entry{}, "Time", "SourceLocation", "JSON"),
+		cmpopts.EquateEmpty(),
+	}
+	if diff := cmp.Diff(got, want, opts); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	for _, e := range got {
+		if e.Time.IsZero() {
+			t.Errorf("entry %q has no time", e.Message)
+		}
+		if e.SourceLocation == nil || e.SourceLocation.File == "" || e.SourceLocation.Line == 0 {
+			t.Errorf("entry %q has no source location: %+v", e.Message, e.SourceLocation)
+		}
+	}
+	if err := h.Err(); err != nil {
+		t.Error(err)
+	}
+
+	if v, ok := got[0].Attr("g", "b"); !ok || v != "two" {
+		t.Errorf("Attr(g, b) = %v, %t; want two, true", v, ok)
+	}
+	if v, ok := got[0].Attr("g", "c"); ok {
+		t.Errorf("Attr(g, c) = %v, true; want false", v)
+	}
+	errs := h.Filter(func(e aelogtest.Entry) bool { return e.Severity == "ERROR" })
+	if diff := cmp.Diff(errs, want[1:], opts); diff != "" {
+		t.Error("Filter: -got +want", diff)
+	}
+	if diff := cmp.Diff(h.Messages(), []string{"debug", "error"}); diff != "" {
+		t.Error("Messages: -got +want", diff)
+	}
+	h.Reset()
+	if got := h.Entries(); len(got) != 0 {
+		t.Errorf("after Reset, got %d entries", len(got))
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Run("identity", func(t *testing.T) {
+		aelogtest.Run(t, func(h slog.Handler) slog.Handler { return h })
+	})
+	t.Run("decorator", func(t *testing.T) {
+		aelogtest.Run(t, func(h slog.Handler) slog.Handler { return decorator{h} })
+	})
+}
+
+// decorator is a typical handler that wraps another handler.
+type decorator struct{ slog.Handler }
+
+func (d decorator) Handle(ctx context.Context, r slog.Record) error {
+	return d.Handler.Handle(ctx, r)
+}
+
+func (d decorator) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return decorator{d.Handler.WithAttrs(attrs)}
+}
+
+func (d decorator) WithGroup(name string) slog.Handler {
+	return decorator{d.Handler.WithGroup(name)}
+}
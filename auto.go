@@ -0,0 +1,57 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"io"
+	"log/slog"
+	"os"
+)
+
+// NewAutoHandler returns a [Handler] created by [NewHandler] when running on
+// Google Cloud, and a human-readable handler created by [NewDevHandler]
+// otherwise, for example on a developer machine.  It considers the process to
+// run on Google Cloud if w isn’t a terminal and either one of the environment
+// variables GAE_SERVICE or K_SERVICE is set (App Engine, Cloud Run, and Cloud
+// Run functions) or the [metadata server] is reachable (Compute Engine and
+// GKE).  In both cases, NewAutoHandler passes on basicOpts and extOpts.
+//
+// [metadata server]: https://cloud.google.com/compute/docs/metadata/overview
+func NewAutoHandler(w io.Writer, basicOpts *slog.HandlerOptions, extOpts *Options) slog.Handler {
+	if !isTerminal(w) && onGoogleCloud() {
+		return NewHandler(w, basicOpts, extOpts)
+	}
+	return NewDevHandler(w, basicOpts, extOpts)
+}
+
+// onGoogleCloud returns whether the process runs on Google Cloud.
+func onGoogleCloud() bool {
+	for _, v := range serviceEnvVars {
+		if os.Getenv(v.service) != "" {
+			return true
+		}
+	}
+	return metadataProjectID() != ""
+}
+
+// isTerminal returns whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,53 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/phst/aelog"
+)
+
+func TestNewAutoHandler(t *testing.T) {
+	// Make sure we don’t detect Google Cloud using the metadata server.
+	metadata := httptest.NewServer(http.NotFoundHandler())
+	defer metadata.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(metadata.URL, "http://"))
+	aelog.ResetMetadataCache()
+	t.Cleanup(aelog.ResetMetadataCache)
+
+	for _, tc := range []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"K_SERVICE": "run"}, "{\"time\":"},
+		{map[string]string{"GAE_SERVICE": "default"}, "{\"time\":"},
+		{nil, "time="},
+	} {
+		for _, name := range []string{"GAE_SERVICE", "K_SERVICE"} {
+			t.Setenv(name, tc.env[name])
+		}
+		buf := new(bytes.Buffer)
+		slog.New(aelog.NewAutoHandler(buf, nil, &aelog.Options{ProjectID: "test"})).Info("info")
+		if got := buf.String(); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("environment %v: got %q, want prefix %q", tc.env, got, tc.want)
+		}
+	}
+}
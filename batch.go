@@ -0,0 +1,190 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// BatchKey is the key of the attribute that contains the records collected by
+// [Batch].
+const BatchKey = "records"
+
+// Batch returns a derived context that collects all records logged with it
+// (or a context derived from it) through a [Handler] instead of writing them
+// immediately.  Calling the returned function writes all collected records as
+// a single log entry whose attribute [BatchKey] is an array of the records.
+// The severity of the entry is the highest severity of the collected records.
+// This reduces the number of log entries for high-volume loops while keeping
+// the details available.  Records that contribute to the entry don’t repeat
+// the HTTP request, trace information, schema version, and the labels of the
+// handler; the entry itself has them.  If the records don’t fit into a single
+// entry of at most [Options.MaxEntryBytes] or 256 KiB, the function writes
+// several entries.  Records at [LevelAlert] or above are never collected if
+// the handler has a [Options.Notify] function, so that the notification
+// isn’t delayed.  Records logged after calling the returned function are
+// written normally.  The returned function is safe to call multiple times;
+// only the first call has an effect.
+//
+//	ctx, flush := aelog.Batch(ctx)
+//	defer flush()
+//	for _, item := range items {
+//		slog.DebugContext(ctx, "processing", "item", item)
+//	}
+func Batch(ctx context.Context) (context.Context, func()) {
+	b := &batch{ctx: ctx}
+	return context.WithValue(ctx, batchKey, b), b.flush
+}
+
+type batch struct {
+	// Context passed to Batch, used to write the entry.
+	ctx context.Context
+
+	mu     sync.Mutex
+	closed bool
+	groups []*batchGroup // one per output
+}
+
+// batchGroup contains the records for a single output.
+type batchGroup struct {
+	h       *Handler
+	out     output
+	records []json.RawMessage
+	levels  []slog.Level // parallel to records
+}
+
+// batchSkipKeys contains the keys of attributes that Handler.Handle derives
+// from the context and that the batch entry therefore already contains.
+var batchSkipKeys = map[string]bool{
+	"httpRequest":                          true,
+	"logging.googleapis.com/trace":         true,
+	"logging.googleapis.com/spanId":        true,
+	"logging.googleapis.com/trace_sampled": true,
+	TraceURLKey:                            true,
+	SchemaVersionKey:                       true,
+	ServiceContextKey:                      true,
+}
+
+// add adds a record to the batch.  It returns false if the batch has already
+// been written and the caller should write the record normally.
+func (b *batch) add(h *Handler, out output, e entry) bool {
+	r := e.Record
+	s := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		// Labels that differ from the handler’s come from the record
+		// or the context and need to stay.
+		if !batchSkipKeys[a.Key] && !(a.Key == LabelsKey && slices.EqualFunc(a.Value.Group(), h.labels, slog.Attr.Equal)) {
+			s.AddAttrs(a)
+		}
+		return true
+	})
+	e.Record = s
+	rec := json.RawMessage(bytes.TrimSpace(out.enc.appendEntry(nil, e)))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+	for _, g := range b.groups {
+		// The batch entry gets the labels of the handler, so only
+		// records with the same labels can share it.
+		if g.out.w == out.w && slices.EqualFunc(g.h.labels, h.labels, slog.Attr.Equal) {
+			g.records = append(g.records, rec)
+			g.levels = append(g.levels, r.Level)
+			return true
+		}
+	}
+	b.groups = append(b.groups, &batchGroup{h, out, []json.RawMessage{rec}, []slog.Level{r.Level}})
+	return true
+}
+
+func (b *batch) flush() {
+	b.mu.Lock()
+	groups := b.groups
+	b.closed = true
+	b.groups = nil
+	b.mu.Unlock()
+	for _, g := range groups {
+		now := time.Now().UTC()
+		// Determine how much space the records can use by encoding
+		// the entry without them.
+		limit := maxBatchBytes
+		if m := g.out.enc.maxEntryBytes; m > 0 {
+			limit = min(limit, m)
+		}
+		// Leave room for a longer message and severity.
+		limit -= len(g.out.enc.appendLimited(nil, g.entry(b.ctx, now, 0, 0), 0)) + 64
+		for i := 0; i < len(g.records); {
+			j, size := i+1, len(g.records[i])
+			for j < len(g.records) && size+1+len(g.records[j]) <= limit {
+				size += 1 + len(g.records[j])
+				j++
+			}
+			// Like slog.Logger, ignore errors from the handler.
+			_ = g.out.handle(g.entry(b.ctx, now, i, j))
+			i = j
+		}
+	}
+}
+
+// maxBatchBytes is the default maximum size of a batch entry.  Cloud Logging
+// rejects entries larger than 256 KiB.
+const maxBatchBytes = 256 << 10
+
+// entry returns the batch entry for the records with indices i to j
+// (exclusive).
+func (g *batchGroup) entry(ctx context.Context, t time.Time, i, j int) entry {
+	records := g.records[i:j]
+	level := slog.Level(math.MinInt)
+	if i < j {
+		level = slices.Max(g.levels[i:j])
+	}
+	msg := fmt.Sprintf("batch of %d records", len(records))
+	r := slog.NewRecord(t, level, msg, 0)
+	if g.h.schemaVersion != "" {
+		r.AddAttrs(slog.String(SchemaVersionKey, g.h.schemaVersion))
+	}
+	labels, svc := g.h.envFields(level, g.h.labels, g.h.serviceContext)
+	if len(labels) > 0 {
+		r.AddAttrs(slog.Attr{Key: LabelsKey, Value: slog.GroupValue(labels...)})
+	}
+	if len(svc) > 0 {
+		r.AddAttrs(slog.Attr{Key: ServiceContextKey, Value: slog.GroupValue(svc...)})
+	}
+	r.AddAttrs(operationAttrs(ctx, false, false)...)
+	trace := g.h.trace(ctx)
+	var projectID string
+	if trace.ID != "" {
+		projectID = g.h.projectID()
+	}
+	r.AddAttrs(appendHTTPAttrs(nil, ctx, projectID, trace)...)
+	r.AddAttrs(slog.Any(BatchKey, records))
+	return entry{Record: r}
+}
+
+func batchFromContext(ctx context.Context) *batch {
+	b, _ := ctx.Value(batchKey).(*batch)
+	return b
+}
+
+const batchKey contextKey = 5
@@ -0,0 +1,171 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestBatch(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{Level: aelog.LevelDebug}, &aelog.Options{ProjectID: "test"}))
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "abc"})
+
+	batchCtx, flush := aelog.Batch(ctx)
+	log.DebugContext(batchCtx, "first", "i", 1)
+	log.WithGroup("g").WarnContext(batchCtx, "second", "i", 2)
+	if buf.Len() != 0 {
+		t.Errorf("batch written before flush: %s", buf)
+	}
+	flush()
+	flush()
+	log.InfoContext(batchCtx, "after")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{
+			"severity":                     "WARNING",
+			"message":                      "batch of 2 records",
+			"logging.googleapis.com/trace": "projects/test/traces/abc",
+			"records": []any{
+				map[string]any{"severity": "DEBUG", "message": "first", "i": 1.0},
+				map[string]any{"severity": "WARNING", "message": "second", "g": map[string]any{"i": 2.0}},
+			},
+		},
+		{
+			"severity":                     "INFO",
+			"message":                      "after",
+			"logging.googleapis.com/trace": "projects/test/traces/abc",
+		},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestBatch_schemaAndLabels(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{
+		SchemaVersion: aelog.SchemaVersion,
+		Labels:        map[string]string{"env": "prod"},
+	}))
+	ctx, flush := aelog.Batch(context.Background())
+	log.InfoContext(ctx, "plain")
+	log.InfoContext(ctx, "labeled", aelog.Label("k", "v"))
+	flush()
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity":                      "INFO",
+		"message":                       "batch of 2 records",
+		aelog.SchemaVersionKey:          aelog.SchemaVersion,
+		"logging.googleapis.com/labels": map[string]any{"env": "prod"},
+		"records": []any{
+			map[string]any{"severity": "INFO", "message": "plain"},
+			map[string]any{
+				"severity":                      "INFO",
+				"message":                       "labeled",
+				"logging.googleapis.com/labels": map[string]any{"env": "prod", "k": "v"},
+			},
+		},
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestBatch_split(t *testing.T) {
+	const max = 1000
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{MaxEntryBytes: max}))
+	ctx, flush := aelog.Batch(context.Background())
+	for i := range 20 {
+		level := aelog.LevelInfo
+		if i == 15 {
+			level = aelog.LevelError
+		}
+		log.Log(ctx, level, "record", "i", i, "pad", strings.Repeat("x", 100))
+	}
+	flush()
+
+	entries, err := aelog.DecodeEntries(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("got %d entries, want several", len(entries))
+	}
+	n := 0
+	for _, e := range entries {
+		if len(e.JSON) > max {
+			t.Errorf("entry has %d bytes, want at most %d", len(e.JSON), max)
+		}
+		if _, ok := e.Attrs[aelog.TruncatedKey]; ok {
+			t.Errorf("entry %q was truncated", e.Message)
+		}
+		records := e.Attrs[aelog.BatchKey].([]any)
+		wantSeverity := "INFO"
+		for _, r := range records {
+			if r.(map[string]any)["i"] == 15.0 {
+				wantSeverity = "ERROR"
+			}
+		}
+		if e.Severity != wantSeverity {
+			t.Errorf("entry %q has severity %s, want %s", e.Message, e.Severity, wantSeverity)
+		}
+		if got, want := e.Message, fmt.Sprintf("batch of %d records", len(records)); got != want {
+			t.Errorf("message = %q, want %q", got, want)
+		}
+		for _, r := range records {
+			if got := r.(map[string]any)["i"]; got != float64(n) {
+				t.Errorf("record %d: i = %v", n, got)
+			}
+			n++
+		}
+	}
+	if n != 20 {
+		t.Errorf("got %d records, want 20", n)
+	}
+}
+
+func TestBatch_notify(t *testing.T) {
+	buf := new(bytes.Buffer)
+	notified := make(chan []byte, 1)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{Notify: func(b []byte) { notified <- b }}))
+	ctx, flush := aelog.Batch(context.Background())
+	defer flush()
+	log.InfoContext(ctx, "collected")
+	log.Log(ctx, aelog.LevelAlert, "alert")
+	select {
+	case <-notified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification")
+	}
+	got := parseRecords(t, buf)
+	want := []map[string]any{{"severity": "ALERT", "message": "alert"}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// LogBudget limits the volume of records that a [Handler] writes per time
+// window.  Once the budget for a window is exhausted, the handler only counts
+// further records below [LevelError] instead of writing them.  After the
+// window ends, the handler writes a summary record at [LevelWarn] with the
+// number of suppressed records per message.  With [Logf], the message is the
+// template, so the summary groups the suppressed records by template.  To keep
+// the summary small, it lists at most 100 distinct messages per window and
+// counts the records with other messages in a separate “suppressedOther”
+// field.  The summary is written lazily when handling the first record after
+// the window.
+type LogBudget struct {
+	// Maximum number of records per window.  If zero, the number of
+	// records isn’t limited.
+	Records int
+
+	// Maximum number of bytes per window.  If zero, the number of bytes
+	// isn’t limited.
+	Bytes int64
+
+	// Length of a window.  If zero, the handler uses one minute.
+	Window time.Duration
+}
+
+// SuppressedKey is the key of the attribute that lists the suppressed
+// records in a log budget summary.  See [LogBudget].
+const SuppressedKey = "suppressed"
+
+// maxSuppressedMessages is the maximum number of distinct messages that a log
+// budget summary lists.
+const maxSuppressedMessages = 100
+
+// budget tracks the log budget of a handler and all handlers derived from
+// it.
+type budget struct {
+	LogBudget
+
+	mu         sync.Mutex
+	start      time.Time      // start of the current window
+	records    int            // records written in the current window
+	bytesStart int64          // bytes written before the current window
+	suppressed map[string]int // message → number of suppressed records
+	other      int            // suppressed records not in suppressed
+}
+
+// check counts a record and returns whether the handler should write it.
+// written is the total number of bytes written so far.  If the previous
+// window has ended and records were suppressed in it, check also returns a
+// summary record to write.
+func (b *budget) check(level slog.Level, msg string, written int64) (bool, *slog.Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	var summary *slog.Record
+	if now.Sub(b.start) >= b.Window {
+		summary = b.summary(now)
+		b.start = now
+		b.records = 0
+		b.bytesStart = written
+		clear(b.suppressed)
+		b.other = 0
+	}
+	exceeded := (b.Records > 0 && b.records >= b.Records) || (b.Bytes > 0 && written-b.bytesStart >= b.Bytes)
+	if exceeded && level < LevelError {
+		if _, ok := b.suppressed[msg]; ok || len(b.suppressed) < maxSuppressedMessages {
+			b.suppressed[msg]++
+		} else {
+			b.other++
+		}
+		return false, summary
+	}
+	b.records++
+	return true, summary
+}
+
+// summary returns a summary record for the suppressed records, or nil if
+// there are none.  b.mu must be locked.
+func (b *budget) summary(now time.Time) *slog.Record {
+	if len(b.suppressed) == 0 {
+		return nil
+	}
+	type suppression struct {
+		Message string `json:"message"`
+		Count   int    `json:"count"`
+	}
+	entries := make([]suppression, 0, len(b.suppressed))
+	total := b.other
+	for msg, n := range b.suppressed {
+		entries = append(entries, suppression{msg, n})
+		total += n
+	}
+	slices.SortFunc(entries, func(a, b suppression) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Message, b.Message)
+	})
+	r := slog.NewRecord(now.UTC(), LevelWarn, "log budget exceeded", 0)
+	r.AddAttrs(
+		slog.Int("suppressedTotal", total),
+		slog.Any(SuppressedKey, entries),
+	)
+	if b.other > 0 {
+		r.AddAttrs(slog.Int("suppressedOther", b.other))
+	}
+	return &r
+}
@@ -0,0 +1,110 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestOptions_LogBudget(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{
+		LogBudget: &aelog.LogBudget{Records: 2, Window: 500 * time.Millisecond},
+	}))
+
+	log.Info("first")
+	log.Info("second")
+	log.Info("third")
+	log.Warn("fourth")
+	log.Info("third")
+	log.Error("error")
+	time.Sleep(600 * time.Millisecond)
+	log.Info("next window")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{"severity": "INFO", "message": "first"},
+		{"severity": "INFO", "message": "second"},
+		{"severity": "ERROR", "message": "error"},
+		{
+			"severity":        "WARNING",
+			"message":         "log budget exceeded",
+			"suppressedTotal": 3.0,
+			"suppressed": []any{
+				map[string]any{"message": "third", "count": 2.0},
+				map[string]any{"message": "fourth", "count": 1.0},
+			},
+		},
+		{"severity": "INFO", "message": "next window"},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestOptions_LogBudget_bytes(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{
+		LogBudget: &aelog.LogBudget{Bytes: 10},
+	}))
+
+	log.Info("first")
+	log.Info("second")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{"severity": "INFO", "message": "first"},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestOptions_LogBudget_manyMessages(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{
+		LogBudget: &aelog.LogBudget{Records: 1, Window: 500 * time.Millisecond},
+	}))
+
+	log.Info("first")
+	for i := range 150 {
+		log.Info("message " + strconv.Itoa(i))
+	}
+	time.Sleep(600 * time.Millisecond)
+	log.Info("next window")
+
+	got := parseRecords(t, buf)
+	if len(got) != 3 {
+		t.Fatalf("got %d records, want 3", len(got))
+	}
+	summary := got[1]
+	if got, want := summary["suppressedTotal"], 150.0; got != want {
+		t.Errorf("suppressedTotal = %v, want %v", got, want)
+	}
+	if got, want := summary["suppressedOther"], 50.0; got != want {
+		t.Errorf("suppressedOther = %v, want %v", got, want)
+	}
+	if got, want := len(summary["suppressed"].([]any)), 100; got != want {
+		t.Errorf("got %d suppressed messages, want %d", got, want)
+	}
+}
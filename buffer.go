@@ -0,0 +1,174 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// NewBufferedWriter creates a new [BufferedWriter] that writes to w.  Passing
+// nil options has the same effect as passing a pointer to a zero struct.
+func NewBufferedWriter(w io.Writer, opts *BufferOptions) *BufferedWriter {
+	if opts == nil {
+		opts = new(BufferOptions)
+	}
+	size := opts.Size
+	if size <= 0 {
+		size = 64 << 10
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &BufferedWriter{w: w, size: size, interval: interval, onError: opts.OnError}
+}
+
+// BufferedWriter is an [io.Writer] that coalesces writes and passes them on to
+// another writer in batches, once the buffered data reaches a size limit or
+// after a time interval, whichever comes first.  This reduces the number of
+// system calls for high-volume logging.  Use it as the writer of a [Handler]
+// and call [Handler.Flush] or [Handler.Close] before the process exits, for
+// example when Cloud Run sends SIGTERM before shutting down an instance;
+// otherwise, the last records might get lost.  [Fatal] and [LogPanic] flush
+// the default handler automatically.  BufferedWriter only splits the output
+// between writes, so records stay intact.  It’s safe for concurrent use.
+//
+// If the underlying writer fails, BufferedWriter keeps the data that it
+// couldn’t write and tries again after the interval.  While flushing fails,
+// it buffers up to four times the size limit; beyond that, Write returns the
+// error of the last failed flush, so that a [Handler] can pass the record to
+// its [Options.FallbackWriter].
+//
+// Use [NewBufferedWriter] to create BufferedWriter objects.
+type BufferedWriter struct {
+	w        io.Writer
+	size     int
+	interval time.Duration
+	onError  func(error)
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	timer  *time.Timer // pending flush; nil if none
+	err    error       // error of the last flush; nil if it succeeded
+	closed bool
+}
+
+// BufferOptions contains options for a [BufferedWriter].
+type BufferOptions struct {
+	// Flush once the buffer contains at least this many bytes.  If zero,
+	// use 64 KiB.
+	Size int
+
+	// Flush once data has been in the buffer for this long.  If zero, use
+	// one second.
+	Interval time.Duration
+
+	// If not nil, OnError is called with the errors of flushes that
+	// BufferedWriter starts by itself, that is, when the buffer is full or
+	// the interval has passed.  [BufferedWriter.Flush] and
+	// [BufferedWriter.Close] return their errors instead.  OnError must
+	// not write to the BufferedWriter.
+	OnError func(error)
+}
+
+// Write implements [io.Writer.Write].  After [BufferedWriter.Close], Write
+// writes directly to the underlying writer.
+func (w *BufferedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return w.w.Write(b)
+	}
+	if w.err != nil && w.buf.Len()+len(b) > 4*w.size {
+		err := w.err
+		w.mu.Unlock()
+		return 0, err
+	}
+	w.buf.Write(b)
+	var err error
+	// While flushing fails, only the timer retries.
+	if w.buf.Len() >= w.size && w.err == nil {
+		err = w.flushLocked()
+	}
+	w.scheduleLocked()
+	w.mu.Unlock()
+	w.report(err)
+	return len(b), nil
+}
+
+// Flush writes all buffered data to the underlying writer.  If that fails,
+// the data stays in the buffer.
+func (w *BufferedWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.flushLocked()
+	w.scheduleLocked()
+	return err
+}
+
+// Close flushes the writer and stops the flush timer.  It doesn’t close the
+// underlying writer.
+func (w *BufferedWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return w.flushLocked()
+}
+
+// flushLocked implements Flush.  It keeps the data that the underlying
+// writer didn’t accept.  w.mu must be locked.
+func (w *BufferedWriter) flushLocked() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.buf.Len() == 0 {
+		w.err = nil
+		return nil
+	}
+	n, err := w.w.Write(w.buf.Bytes())
+	w.buf.Next(n)
+	w.err = err
+	if err == nil {
+		w.buf.Reset()
+	}
+	return err
+}
+
+// scheduleLocked starts the flush timer if there is buffered data and no
+// flush is pending.  w.mu must be locked.
+func (w *BufferedWriter) scheduleLocked() {
+	if w.timer != nil || w.closed || w.buf.Len() == 0 {
+		return
+	}
+	w.timer = time.AfterFunc(w.interval, func() {
+		w.mu.Lock()
+		err := w.flushLocked()
+		w.scheduleLocked()
+		w.mu.Unlock()
+		w.report(err)
+	})
+}
+
+// report passes err to the OnError function if both aren’t nil.  w.mu must
+// not be locked.
+func (w *BufferedWriter) report(err error) {
+	if err != nil && w.onError != nil {
+		w.onError(err)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phst/aelog"
+)
+
+func TestBufferedWriter(t *testing.T) {
+	out := new(syncBuffer)
+	h := aelog.NewHandler(aelog.NewBufferedWriter(out, &aelog.BufferOptions{Size: 1 << 20, Interval: time.Hour}), nil, nil)
+	log := slog.New(h)
+
+	log.Info("first")
+	if n := out.Len(); n != 0 {
+		t.Errorf("underlying writer received %d bytes before flushing", n)
+	}
+	if err := h.Flush(); err != nil {
+		t.Error(err)
+	}
+	if got := len(parseRecords(t, bytes.NewReader(out.Bytes()))); got != 1 {
+		t.Errorf("got %d records after flushing, want one", got)
+	}
+	log.Info("second")
+	if err := h.Close(); err != nil {
+		t.Error(err)
+	}
+	if got := len(parseRecords(t, bytes.NewReader(out.Bytes()))); got != 2 {
+		t.Errorf("got %d records after closing, want two", got)
+	}
+}
+
+func TestBufferedWriter_limits(t *testing.T) {
+	out := new(syncBuffer)
+	w := aelog.NewBufferedWriter(out, &aelog.BufferOptions{Size: 10, Interval: 10 * time.Millisecond})
+	defer w.Close()
+
+	w.Write([]byte("12345\n"))
+	if n := out.Len(); n != 0 {
+		t.Errorf("underlying writer received %d bytes before reaching the limits", n)
+	}
+	w.Write([]byte("67890\n"))
+	if got, want := string(out.Bytes()), "12345\n67890\n"; got != want {
+		t.Errorf("after reaching the size limit: got %q, want %q", got, want)
+	}
+	w.Write([]byte("abc\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for out.Len() < 16 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got, want := string(out.Bytes()), "12345\n67890\nabc\n"; got != want {
+		t.Errorf("after the interval: got %q, want %q", got, want)
+	}
+}
+
+func TestBufferedWriter_errors(t *testing.T) {
+	out := &brokenWriter{fail: true}
+	errs := make(chan error, 10)
+	w := aelog.NewBufferedWriter(out, &aelog.BufferOptions{
+		Size:     10,
+		Interval: 10 * time.Millisecond,
+		OnError:  func(err error) { errs <- err },
+	})
+	defer w.Close()
+
+	for _, s := range []string{"12345\n", "67890\n"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Errorf("Write(%q): %v", s, err)
+		}
+	}
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError wasn’t called")
+	}
+	// The buffer is full now.
+	if _, err := w.Write(bytes.Repeat([]byte("x"), 40)); err == nil {
+		t.Error("Write succeeded even though the buffer is full")
+	}
+	if err := w.Flush(); err == nil {
+		t.Error("Flush succeeded even though the writer fails")
+	}
+
+	// Once the writer works again, nothing is lost.
+	out.setFail(false)
+	if err := w.Flush(); err != nil {
+		t.Error(err)
+	}
+	if got, want := string(out.Bytes()), "12345\n67890\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// brokenWriter is a writer that fails while fail is set.
+type brokenWriter struct {
+	syncBuffer
+	mu   sync.Mutex
+	fail bool
+}
+
+func (w *brokenWriter) setFail(fail bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.fail = fail
+}
+
+func (w *brokenWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	fail := w.fail
+	w.mu.Unlock()
+	if fail {
+		return 0, errors.New("broken")
+	}
+	return w.syncBuffer.Write(p)
+}
+
+// syncBuffer is a [bytes.Buffer] that’s safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/phst/aelog"
+)
+
+// entryFromRecord converts a JSON record written by an aelog.Handler to a
+// LogEntry in the format of the Cloud Logging API.  Special fields become
+// LogEntry fields; all other fields, including the message, go into the JSON
+// payload.  See
+// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields.
+func entryFromRecord(b []byte) (map[string]any, error) {
+	rec, err := aelog.ParseEntry(b)
+	if err != nil {
+		return nil, err
+	}
+	e := make(map[string]any)
+	if !rec.Time.IsZero() {
+		// The Logging API only accepts RFC 3339 timestamps, not the
+		// numeric ones of aelog.TimeFormatUnixNano.
+		e["timestamp"] = rec.Time.Format(time.RFC3339Nano)
+	}
+	set(e, "severity", rec.Severity)
+	set(e, "trace", rec.Trace)
+	set(e, "spanId", rec.SpanID)
+	set(e, "traceSampled", rec.TraceSampled)
+	if len(rec.Labels) > 0 {
+		e["labels"] = rec.Labels
+	}
+	if r := rec.HTTPRequest; r != nil {
+		m := make(map[string]any)
+		set(m, "requestMethod", r.RequestMethod)
+		set(m, "requestUrl", r.RequestURL)
+		set(m, "requestSize", r.RequestSize)
+		set(m, "status", r.Status)
+		set(m, "responseSize", r.ResponseSize)
+		set(m, "userAgent", r.UserAgent)
+		set(m, "remoteIp", r.RemoteIP)
+		set(m, "serverIp", r.ServerIP)
+		set(m, "referer", r.Referer)
+		if r.Latency != 0 {
+			m["latency"] = aelog.FormatDuration(r.Latency)
+		}
+		set(m, "protocol", r.Protocol)
+		e["httpRequest"] = m
+	}
+	if l := rec.SourceLocation; l != nil {
+		m := make(map[string]any)
+		set(m, "file", l.File)
+		set(m, "line", l.Line)
+		set(m, "function", l.Function)
+		e["sourceLocation"] = m
+	}
+	if o := rec.Operation; o != nil {
+		m := make(map[string]any)
+		set(m, "id", o.ID)
+		set(m, "producer", o.Producer)
+		set(m, "first", o.First)
+		set(m, "last", o.Last)
+		e["operation"] = m
+	}
+	payload := rec.Attrs
+	if id, ok := payload[insertIDKey]; ok {
+		e["insertId"] = id
+		delete(payload, insertIDKey)
+	}
+	if rec.Message != "" {
+		payload[aelog.MessageKey] = rec.Message
+	}
+	if len(payload) > 0 {
+		e["jsonPayload"] = payload
+	}
+	return e, nil
+}
+
+// insertIDKey is the special key for the insert ID, which [aelog.Entry]
+// doesn’t decode.
+const insertIDKey = "logging.googleapis.com/insertId"
+
+// set sets m[k] to v unless v is the zero value.
+func set[T comparable](m map[string]any, k string, v T) {
+	var zero T
+	if v != zero {
+		m[k] = v
+	}
+}
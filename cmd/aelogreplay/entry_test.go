@@ -0,0 +1,72 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEntryFromRecord(t *testing.T) {
+	rec := `{"time":"2026-01-02T03:04:05.123Z","severity":"ERROR","message":"boom",` +
+		`"logging.googleapis.com/trace":"projects/p/traces/abc","logging.googleapis.com/spanId":"123",` +
+		`"httpRequest":{"requestMethod":"GET"},"user":"alice"}`
+	got, err := entryFromRecord([]byte(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"timestamp":   "2026-01-02T03:04:05.123Z",
+		"severity":    "ERROR",
+		"trace":       "projects/p/traces/abc",
+		"spanId":      "123",
+		"httpRequest": map[string]any{"requestMethod": "GET"},
+		"jsonPayload": map[string]any{"message": "boom", "user": "alice"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	for _, invalid := range []string{"", "null", "[]", "{", `{"severity":1}`} {
+		if e, err := entryFromRecord([]byte(invalid)); err == nil {
+			t.Errorf("entryFromRecord(%q) = %v, want error", invalid, e)
+		}
+	}
+}
+
+func TestEntryFromRecord_special(t *testing.T) {
+	rec := `{"time":1767323045123000000,"severity":"INFO","message":"hi",` +
+		`"logging.googleapis.com/labels":{"env":"prod"},"logging.googleapis.com/insertId":"42",` +
+		`"logging.googleapis.com/sourceLocation":{"file":"main.go","line":12,"function":"main.main"},` +
+		`"logging.googleapis.com/operation":{"id":"op","first":true},` +
+		`"httpRequest":{"status":200,"latency":"1.5s"}}`
+	got, err := entryFromRecord([]byte(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"timestamp":      "2026-01-02T03:04:05.123Z",
+		"severity":       "INFO",
+		"labels":         map[string]string{"env": "prod"},
+		"insertId":       "42",
+		"sourceLocation": map[string]any{"file": "main.go", "line": 12, "function": "main.main"},
+		"operation":      map[string]any{"id": "op", "first": true},
+		"httpRequest":    map[string]any{"status": 200, "latency": "1.5s"},
+		"jsonPayload":    map[string]any{"message": "hi"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
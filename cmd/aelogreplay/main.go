@@ -0,0 +1,174 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command aelogreplay writes log records previously emitted by an
+// [aelog.Handler] to the Cloud Logging API.  Use it to backfill records that
+// the logging agent didn’t pick up, for example records salvaged from the
+// disk of a crashed instance.  The log entries keep their original
+// timestamps, severities, and special fields.
+//
+// Usage:
+//
+//	aelogreplay -project=PROJECT [flags] [FILE…]
+//
+// aelogreplay reads JSON lines from the given files, or from standard input
+// if there are none.  It uses [Application Default Credentials] to
+// authenticate.  The flags are:
+//
+//	-project    project to write to (required)
+//	-log        log name (default “stderr”)
+//	-resource   monitored resource type (default “global”)
+//	-batch      number of entries per write request (default 500)
+//	-n          only print the entries that would be written
+//
+// [Application Default Credentials]: https://cloud.google.com/docs/authentication/application-default-credentials
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"golang.org/x/oauth2/google"
+)
+
+func main() {
+	project := flag.String("project", "", "project to write to")
+	logName := flag.String("log", "stderr", "log name")
+	resource := flag.String("resource", "global", "monitored resource type")
+	batch := flag.Int("batch", 500, "number of entries per write request")
+	dryRun := flag.Bool("n", false, "only print the entries that would be written")
+	flag.Parse()
+	if *project == "" {
+		log.Fatal("aelogreplay: missing -project flag")
+	}
+	if *batch <= 0 {
+		log.Fatal("aelogreplay: -batch must be positive")
+	}
+	ctx := context.Background()
+	r := &replayer{
+		project:  *project,
+		logName:  *logName,
+		resource: *resource,
+		batch:    *batch,
+	}
+	if *dryRun {
+		r.write = func(_ context.Context, body []byte) error {
+			_, err := fmt.Printf("%s\n", body)
+			return err
+		}
+	} else {
+		client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/logging.write")
+		if err != nil {
+			log.Fatal(err)
+		}
+		r.write = func(ctx context.Context, body []byte) error { return writeEntries(ctx, client, body) }
+	}
+	files := flag.Args()
+	if len(files) == 0 {
+		if err := r.replay(ctx, os.Stdin, "standard input"); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = r.replay(ctx, f, name)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+type replayer struct {
+	project, logName, resource string
+	batch                      int
+	write                      func(ctx context.Context, body []byte) error
+}
+
+// replay reads JSON lines from r and writes them in batches.
+func (r *replayer) replay(ctx context.Context, in io.Reader, name string) error {
+	s := bufio.NewScanner(in)
+	s.Buffer(nil, 1<<20)
+	var entries []map[string]any
+	n := 0
+	for s.Scan() {
+		n++
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		e, err := entryFromRecord(line)
+		if err != nil {
+			log.Printf("%s:%d: skipping invalid record: %v", name, n, err)
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) >= r.batch {
+			if err := r.flush(ctx, entries); err != nil {
+				return err
+			}
+			entries = entries[:0]
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if len(entries) > 0 {
+		return r.flush(ctx, entries)
+	}
+	return nil
+}
+
+func (r *replayer) flush(ctx context.Context, entries []map[string]any) error {
+	// https://cloud.google.com/logging/docs/reference/v2/rest/v2/entries/write
+	body, err := json.Marshal(map[string]any{
+		"logName":  fmt.Sprintf("projects/%s/logs/%s", r.project, r.logName),
+		"resource": map[string]any{"type": r.resource},
+		"entries":  entries,
+	})
+	if err != nil {
+		return err
+	}
+	return r.write(ctx, body)
+}
+
+func writeEntries(ctx context.Context, client *http.Client, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://logging.googleapis.com/v2/entries:write", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("aelogreplay: writing entries failed with status %s: %s", resp.Status, b)
+	}
+	return nil
+}
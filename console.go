@@ -0,0 +1,45 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"net/url"
+	"strings"
+)
+
+// TraceURLKey is the key of the attribute containing a link to the trace of
+// the current request.  See [Options.AddTraceURL].
+const TraceURLKey = "traceUrl"
+
+// TraceURL returns the URL of the Google Cloud Console page that shows the
+// given trace.  projectID is the alphanumeric ID of the Google Cloud project,
+// and trace is the bare trace ID as found in the X-Cloud-Trace-Context or
+// traceparent header.
+func TraceURL(projectID, trace string) string {
+	q := url.Values{"project": {projectID}, "tid": {trace}}
+	return "https://console.cloud.google.com/traces/list?" + q.Encode()
+}
+
+// LogsExplorerURL returns the URL of the Google Cloud Console Logs Explorer
+// with the given [query] prefilled.  The Logs Explorer uses the most recently
+// selected project.
+//
+// [query]: https://cloud.google.com/logging/docs/view/logging-query-language
+func LogsExplorerURL(filter string) string {
+	// The Logs Explorer uses matrix parameters, so escape the
+	// parameter separators as well.
+	q := strings.ReplaceAll(url.PathEscape(filter), "=", "%3D")
+	return "https://console.cloud.google.com/logs/query;query=" + q
+}
@@ -0,0 +1,33 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"fmt"
+
+	"github.com/phst/aelog"
+)
+
+func ExampleTraceURL() {
+	fmt.Println(aelog.TraceURL("my-project", "105445aa7843bc8bf206b12000100000"))
+	// Output:
+	// https://console.cloud.google.com/traces/list?project=my-project&tid=105445aa7843bc8bf206b12000100000
+}
+
+func ExampleLogsExplorerURL() {
+	fmt.Println(aelog.LogsExplorerURL(`severity>=ERROR AND jsonPayload.message="a;b"`))
+	// Output:
+	// https://console.cloud.google.com/logs/query;query=severity%3E%3DERROR%20AND%20jsonPayload.message%3D%22a%3Bb%22
+}
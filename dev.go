@@ -0,0 +1,48 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"io"
+	"log/slog"
+)
+
+// NewDevHandler returns an [slog.TextHandler] that writes records in a
+// human-readable format, for example on a developer machine where reading the
+// JSON format of [Handler] is cumbersome.  It displays the time of records in
+// the local time zone, using [Options.DevTimeLayout] if set.  NewDevHandler
+// ignores all other fields of extOpts.  Note that the ReplaceAttr function of
+// basicOpts sees the standard [slog] keys instead of the special keys
+// described in [NewHandler].
+func NewDevHandler(w io.Writer, basicOpts *slog.HandlerOptions, extOpts *Options) slog.Handler {
+	if extOpts == nil || extOpts.DevTimeLayout == "" {
+		return slog.NewTextHandler(w, basicOpts)
+	}
+	var opts slog.HandlerOptions
+	if basicOpts != nil {
+		opts = *basicOpts
+	}
+	replace, layout := opts.ReplaceAttr, extOpts.DevTimeLayout
+	opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if replace != nil {
+			a = replace(groups, a)
+		}
+		if len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+			a.Value = slog.StringValue(a.Value.Time().Local().Format(layout))
+		}
+		return a
+	}
+	return slog.NewTextHandler(w, &opts)
+}
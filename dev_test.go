@@ -0,0 +1,60 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/phst/aelog"
+)
+
+func TestNewDevHandler(t *testing.T) {
+	tm := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		opts *aelog.Options
+		want string
+	}{
+		{"default", nil, fmt.Sprintf("time=%s level=INFO msg=info\n", tm.Format("2006-01-02T15:04:05.000Z07:00"))},
+		{"layout", &aelog.Options{DevTimeLayout: "15:04"}, fmt.Sprintf("time=%s level=INFO msg=info\n", tm.Local().Format("15:04"))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			// ReplaceAttr still sees the time value.
+			var replaced bool
+			replace := func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+					replaced = true
+				}
+				return a
+			}
+			h := aelog.NewDevHandler(buf, &slog.HandlerOptions{ReplaceAttr: replace}, tc.opts)
+			if err := h.Handle(context.Background(), slog.NewRecord(tm, aelog.LevelInfo, "info", 0)); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if !replaced {
+				t.Error("ReplaceAttr didn’t see the time")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the output format of a [Handler].  See [Options.Format].
+type Format int
+
+const (
+	// Cloud Logging structured logging format.  This is the default.
+	FormatCloudLogging Format = iota
+
+	// [Elastic Common Schema] format, for shipping logs to Elasticsearch
+	// or OpenSearch when not running on Google Cloud.  The handler
+	// translates the top-level special fields to their ECS equivalents
+	// using dotted field names:
+	//
+	//   - time → “@timestamp”
+	//   - severity → “log.level”, in lowercase
+	//   - sourceLocation → “log.origin.file.name”,
+	//     “log.origin.file.line”, and “log.origin.function”
+	//   - labels → “labels”
+	//   - trace and span ID → “trace.id” and “span.id”
+	//   - httpRequest → “http.request.method”, “url.original”,
+	//     “http.response.status_code”, “event.duration” (in
+	//     nanoseconds), etc.
+	//   - serviceContext → “service.name” and “service.version”
+	//   - [StackTraceKey] → “error.stack_trace”
+	//
+	// It also adds the field “ecs.version”.  Other attributes, including
+	// the message and [Error] attributes, are already compatible.
+	// [Options.ReplaceAttr] and ReplaceValue see the attributes before
+	// the translation.
+	//
+	// [Elastic Common Schema]: https://www.elastic.co/guide/en/ecs/current/index.html
+	FormatECS
+)
+
+// ecsVersion is the ECS version that FormatECS implements.
+const ecsVersion = "8.11.0"
+
+// ecsAttr translates a top-level attribute that has already been replaced to
+// ECS.  For special fields that ECS represents as multiple fields with dotted
+// keys, it returns an empty attribute and the fields.
+func ecsAttr(a slog.Attr) (slog.Attr, []slog.Attr) {
+	v := a.Value
+	switch a.Key {
+	case TimeKey:
+		a.Key = "@timestamp"
+	case SeverityKey:
+		a.Key = "log.level"
+		if v.Kind() == slog.KindString {
+			a.Value = slog.StringValue(strings.ToLower(v.String()))
+		}
+	case SourceLocationKey:
+		return slog.Attr{}, ecsGroup(v, map[string]string{
+			"file":     "log.origin.file.name",
+			"line":     "log.origin.file.line",
+			"function": "log.origin.function",
+		})
+	case LabelsKey:
+		a.Key = "labels"
+	case "logging.googleapis.com/trace":
+		a.Key = "trace.id"
+		if v.Kind() == slog.KindString {
+			// Remove the “projects/PROJECT/traces/” prefix.
+			s := v.String()
+			a.Value = slog.StringValue(s[strings.LastIndexByte(s, '/')+1:])
+		}
+	case "logging.googleapis.com/spanId":
+		a.Key = "span.id"
+	case "logging.googleapis.com/trace_sampled":
+		return slog.Attr{}, nil
+	case "httpRequest":
+		return slog.Attr{}, ecsGroup(v, map[string]string{
+			"requestMethod": "http.request.method",
+			"requestUrl":    "url.original",
+			"requestSize":   "http.request.bytes",
+			"status":        "http.response.status_code",
+			"responseSize":  "http.response.bytes",
+			"userAgent":     "user_agent.original",
+			"remoteIp":      "client.address",
+			"serverIp":      "server.address",
+			"referer":       "http.request.referrer",
+			"latency":       "event.duration",
+			"protocol":      "http.version",
+		})
+	case ServiceContextKey:
+		return slog.Attr{}, ecsGroup(v, map[string]string{
+			"service": "service.name",
+			"version": "service.version",
+		})
+	case StackTraceKey:
+		a.Key = "error.stack_trace"
+	}
+	return a, nil
+}
+
+// ecsGroup flattens the members of the group v, renaming them according to
+// keys.  It drops members that aren’t in keys.  It converts numbers that the
+// Cloud Logging format represents as strings back to numbers.
+func ecsGroup(v slog.Value, keys map[string]string) []slog.Attr {
+	if v.Kind() != slog.KindGroup {
+		return nil
+	}
+	var attrs []slog.Attr
+	for _, m := range v.Group() {
+		key, ok := keys[m.Key]
+		if !ok {
+			continue
+		}
+		val := m.Value.Resolve()
+		if val.Kind() == slog.KindString {
+			s := val.String()
+			switch key {
+			case "log.origin.file.line", "http.request.bytes", "http.response.bytes":
+				if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+					val = slog.Int64Value(n)
+				}
+			case "event.duration":
+				if d, err := time.ParseDuration(s); err == nil {
+					val = slog.Int64Value(d.Nanoseconds())
+				}
+			case "http.version":
+				val = slog.StringValue(strings.TrimPrefix(s, "HTTP/"))
+			}
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: val})
+	}
+	return attrs
+}
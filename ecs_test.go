@@ -0,0 +1,80 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestFormatECS(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := aelog.NewHandler(buf, &slog.HandlerOptions{AddSource: true}, &aelog.Options{
+		Format:         aelog.FormatECS,
+		Labels:         map[string]string{"env": "test"},
+		ServiceContext: aelog.ServiceContext{Service: "svc", Version: "v1"},
+	})
+	log := slog.New(h)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		log.With("a", 1).WarnContext(r.Context(), "warning", slog.Group("", "b", 2), aelog.StackTraceKey, "stack")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	got := parseRecords(t, buf)
+	if len(got) != 1 {
+		t.Fatalf("got %d records, want one", len(got))
+	}
+	r := got[0]
+	if ts, ok := r["@timestamp"].(string); !ok || !strings.HasSuffix(ts, "Z") {
+		t.Errorf("got @timestamp %v, want UTC time", r["@timestamp"])
+	}
+	if file, ok := r["log.origin.file.name"].(string); !ok || !strings.HasSuffix(file, "ecs_test.go") {
+		t.Errorf("got log.origin.file.name %v, want ecs_test.go", r["log.origin.file.name"])
+	}
+	if line, ok := r["log.origin.file.line"].(float64); !ok || line <= 0 {
+		t.Errorf("got log.origin.file.line %v, want positive number", r["log.origin.file.line"])
+	}
+	want := map[string]any{
+		"log.level":           "warning",
+		"log.origin.function": "github.com/phst/aelog_test.TestFormatECS.func1",
+		"message":             "warning",
+		"ecs.version":         "8.11.0",
+		"labels":              map[string]any{"env": "test"},
+		"service.name":        "svc",
+		"service.version":     "v1",
+		"trace.id":            "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span.id":             "00f067aa0ba902b7",
+		"http.request.method": "GET",
+		"url.original":        "/path",
+		"client.address":      "192.0.2.1:1234",
+		"http.version":        "1.1",
+		"error.stack_trace":   "stack",
+		"a":                   1.0,
+		"b":                   2.0,
+	}
+	if diff := cmp.Diff(r, want, ignoreFields("@timestamp", "log.origin.file.name", "log.origin.file.line")); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
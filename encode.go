@@ -0,0 +1,663 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// encoder writes records as JSON objects in the shape that Cloud Logging
+// expects.  Its output is the same as that of an [slog.JSONHandler] using
+// replaceAttr, followed by the user’s [slog.HandlerOptions.ReplaceAttr] and
+// [Options.ReplaceValue] functions.  However, it writes the built-in fields
+// directly and only calls the replacement functions if there are any, which
+// saves a lot of allocations.
+type encoder struct {
+	level         slog.Leveler
+	addSource     bool
+	maxEntryBytes int
+	redactKeys    []string
+	masks         []Mask
+	severityFunc  func(slog.Level) string
+	trimSource    string
+	format        Format
+	omitTime      bool
+	timeFormat    TimeFormat
+
+	// User-supplied replacement functions, possibly nil.
+	replaceAttr  func(groups []string, a slog.Attr) slog.Attr
+	replaceValue func(key string, v slog.Value) slog.Value
+}
+
+func (e *encoder) enabled(l slog.Level) bool {
+	min := slog.LevelInfo
+	if e.level != nil {
+		min = e.level.Level()
+	}
+	return l >= min
+}
+
+// severity returns the Cloud Logging severity for l.
+func (e *encoder) severity(l slog.Level) string {
+	if e.severityFunc != nil {
+		return e.severityFunc(l)
+	}
+	return severityForLevel(l)
+}
+
+// custom returns whether the encoder has to call user-supplied replacement
+// functions.
+func (e *encoder) custom() bool {
+	return e.replaceAttr != nil || e.replaceValue != nil
+}
+
+// entry is a record ready for encoding.  The attributes of the embedded
+// record are top-level attributes; attrs are the attributes of the original
+// record, which go into the groups of prefix.
+type entry struct {
+	slog.Record
+	prefix *prefix
+	attrs  []slog.Attr
+
+	// Whether the entry has already been truncated.
+	truncated bool
+}
+
+// prefix contains the attributes and groups added by Handler.WithAttrs and
+// Handler.WithGroup in encoded form, so that we don’t have to encode them for
+// every record.
+type prefix struct {
+	// Encoded attributes without leading or trailing comma.  The first
+	// open groups are still open at the end of buf.
+	buf  []byte
+	open int
+
+	// All groups, from outermost to innermost.  The groups after the
+	// first open groups haven’t been written yet, because they don’t
+	// contain any attributes yet.
+	groups []string
+}
+
+// withAttrs returns a prefix that contains the attributes of p followed by
+// attrs, in the innermost group of p.
+func (e *encoder) withAttrs(p *prefix, attrs []slog.Attr) *prefix {
+	s := encodeState{enc: e, buf: slices.Clip(p.buf), sep: len(p.buf) > 0}
+	if !s.appendNested(p, attrs) {
+		return p
+	}
+	return &prefix{s.buf, len(p.groups), p.groups}
+}
+
+// withGroup returns a prefix whose innermost group is name.
+func (p *prefix) withGroup(name string) *prefix {
+	return &prefix{p.buf, p.open, append(slices.Clip(p.groups), name)}
+}
+
+// trackGroups returns whether the encoder needs the names of the open groups.
+func (e *encoder) trackGroups() bool {
+	return e.custom() || len(e.redactKeys) > 0
+}
+
+// appendEntry appends the JSON encoding of r to b, followed by a newline.  If
+// the encoded entry would exceed Options.MaxEntryBytes, appendEntry truncates
+// it.
+func (e *encoder) appendEntry(b []byte, r entry) []byte {
+	start := len(b)
+	b = e.appendLimited(b, r, 0)
+	if e.maxEntryBytes <= 0 || len(b)-start <= e.maxEntryBytes {
+		return b
+	}
+	return e.appendTruncated(b[:start], r)
+}
+
+// appendLimited appends the JSON encoding of r to b, followed by a newline.
+// If limit is positive, it truncates string values and the message to at
+// most limit bytes.
+func (e *encoder) appendLimited(b []byte, r entry, limit int) []byte {
+	s := encodeState{enc: e, buf: append(b, '{'), limit: limit, truncated: r.truncated}
+	if e.custom() || e.format == FormatECS {
+		// Go the slow way so that the replacement functions and the
+		// ECS translation see all built-in attributes.
+		if !r.Time.IsZero() && !e.omitTime {
+			s.appendAttr(slog.Time(slog.TimeKey, r.Time.Round(0)))
+		}
+		s.appendAttr(slog.String(SeverityKey, e.severity(r.Level)))
+		if e.addSource {
+			src := new(slog.Source)
+			if r.PC != 0 {
+				f := e.frame(r.PC)
+				*src = slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+			}
+			s.appendAttr(slog.Any(slog.SourceKey, src))
+		}
+		s.appendAttr(slog.String(slog.MessageKey, r.Message))
+		if e.format == FormatECS {
+			s.appendKey("ecs.version")
+			s.appendString(ecsVersion)
+		}
+	} else {
+		if !r.Time.IsZero() && !e.omitTime {
+			s.appendKey(TimeKey)
+			s.appendTime(r.Time)
+		}
+		s.appendKey(SeverityKey)
+		s.appendString(e.severity(r.Level))
+		if e.addSource && r.PC != 0 {
+			s.appendSource(r.PC)
+		}
+		s.appendKey(MessageKey)
+		s.appendText(r.Message)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		s.appendAttr(a)
+		return true
+	})
+	if p := r.prefix; p != nil {
+		if len(p.buf) > 0 {
+			if s.sep {
+				s.buf = append(s.buf, ',')
+			}
+			s.buf = append(s.buf, p.buf...)
+			s.sep = true
+		}
+		open := p.open
+		if s.appendNested(p, r.attrs) {
+			open = len(p.groups)
+		}
+		for range open {
+			s.buf = append(s.buf, '}')
+		}
+	} else {
+		for _, a := range r.attrs {
+			s.appendAttr(a)
+		}
+	}
+	if s.truncated {
+		s.appendKey(TruncatedKey)
+		s.buf = append(s.buf, "true"...)
+	}
+	return append(s.buf, '}', '\n')
+}
+
+// appendNested appends attrs in the innermost group of p, assuming that
+// s.buf already contains p.buf.  It opens the groups of p that aren’t open
+// yet and leaves them open.  If none of the attributes produce any
+// output, appendNested leaves s.buf unchanged and returns false.
+func (s *encodeState) appendNested(p *prefix, attrs []slog.Attr) bool {
+	s.depth = p.open
+	if s.enc.trackGroups() {
+		// Clip the groups so that openGroup doesn’t modify p.
+		s.groups = slices.Clip(p.groups[:p.open])
+	}
+	pos, sep := len(s.buf), s.sep
+	for _, g := range p.groups[p.open:] {
+		s.openGroup(g)
+	}
+	nonEmpty := false
+	for _, a := range attrs {
+		if s.appendAttr(a) {
+			nonEmpty = true
+		}
+	}
+	if !nonEmpty {
+		s.buf, s.sep = s.buf[:pos], sep
+		return false
+	}
+	return true
+}
+
+// encodeState holds the state for encoding a single record.
+type encodeState struct {
+	enc *encoder
+	buf []byte
+	sep bool // whether the next key needs a preceding comma
+
+	// Open groups.  We only maintain the group names if the encoder
+	// needs them, see encoder.trackGroups.
+	depth  int
+	groups []string
+
+	// If positive, the maximum length of string values in bytes.
+	limit int
+
+	// Whether we had to truncate anything.
+	truncated bool
+}
+
+// appendAttr appends a single attribute, applying the replacement functions.
+// It returns whether it has appended anything.
+func (s *encodeState) appendAttr(a slog.Attr) bool {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		a = s.replace(a)
+	}
+	if s.depth == 0 && s.enc.format == FormatECS {
+		var flat []slog.Attr
+		if a, flat = ecsAttr(a); len(flat) > 0 {
+			for _, m := range flat {
+				s.appendKey(m.Key)
+				s.appendValue(m.Value)
+			}
+			return true
+		}
+	}
+	v := a.Value
+	if a.Key == "" && v.Kind() == slog.KindAny && v.Any() == nil {
+		// Elide empty attributes.
+		return false
+	}
+	if v.Kind() == slog.KindAny {
+		if src, ok := v.Any().(*slog.Source); ok {
+			// Only possible if the user’s ReplaceAttr function
+			// returns a source; do the same as slog.JSONHandler.
+			if src == nil || *src == (slog.Source{}) {
+				return false
+			}
+			v = sourceGroup(src)
+		}
+	}
+	if s.redact(a.Key) {
+		v = slog.StringValue(redacted)
+	}
+	if v.Kind() != slog.KindGroup {
+		s.appendKey(a.Key)
+		s.appendValue(v)
+		return true
+	}
+	attrs := v.Group()
+	if len(attrs) == 0 {
+		return false
+	}
+	// The group might still turn out to be empty, e.g. if ReplaceAttr
+	// removes all its members.  Remember where we are so that we can
+	// roll back in that case.
+	pos, sep := len(s.buf), s.sep
+	if a.Key != "" {
+		s.openGroup(a.Key)
+	}
+	nonEmpty := false
+	for _, m := range attrs {
+		if s.appendAttr(m) {
+			nonEmpty = true
+		}
+	}
+	if a.Key != "" {
+		s.closeGroup()
+	}
+	if !nonEmpty {
+		s.buf, s.sep = s.buf[:pos], sep
+	}
+	return nonEmpty
+}
+
+// replace applies replaceAttr to top-level attributes and then the
+// user-supplied replacement functions.
+func (s *encodeState) replace(a slog.Attr) slog.Attr {
+	if s.depth == 0 {
+		a = replaceAttr(nil, a)
+	}
+	e := s.enc
+	if !e.custom() {
+		return a
+	}
+	if e.replaceAttr != nil {
+		a = e.replaceAttr(s.groups, a)
+	}
+	if e.replaceValue != nil && a.Value.Kind() != slog.KindGroup && !isBuiltin(s.groups, a.Key) {
+		a.Value = e.replaceValue(a.Key, a.Value)
+	}
+	// The replacement functions may return unresolved values.
+	a.Value = a.Value.Resolve()
+	return a
+}
+
+func (s *encodeState) openGroup(name string) {
+	s.appendKey(name)
+	s.buf = append(s.buf, '{')
+	s.sep = false
+	s.depth++
+	if s.enc.trackGroups() {
+		s.groups = append(s.groups, name)
+	}
+}
+
+func (s *encodeState) closeGroup() {
+	s.buf = append(s.buf, '}')
+	s.sep = true
+	s.depth--
+	if s.enc.trackGroups() {
+		s.groups = s.groups[:len(s.groups)-1]
+	}
+}
+
+func (s *encodeState) appendKey(key string) {
+	if s.sep {
+		s.buf = append(s.buf, ',')
+	}
+	s.appendString(key)
+	s.buf = append(s.buf, ':')
+	s.sep = true
+}
+
+// appendText appends a string value, masking it and truncating it to
+// s.limit.
+func (s *encodeState) appendText(str string) {
+	if len(s.enc.masks) > 0 {
+		str = mask(s.enc.masks, str)
+	}
+	if s.limit > 0 && len(str) > s.limit {
+		str = truncate(str, s.limit)
+		s.truncated = true
+	}
+	s.appendString(str)
+}
+
+func (s *encodeState) appendString(str string) {
+	s.buf = append(s.buf, '"')
+	s.buf = appendEscaped(s.buf, str)
+	s.buf = append(s.buf, '"')
+}
+
+// appendSource appends the source location for the program counter pc.  The
+// result is the same as that of replaceAttr.
+func (s *encodeState) appendSource(pc uintptr) {
+	f := s.enc.frame(pc)
+	if f.File == "" && f.Line <= 0 && f.Function == "" {
+		return
+	}
+	s.openGroup(SourceLocationKey)
+	if f.File != "" {
+		s.appendKey("file")
+		s.appendString(f.File)
+	}
+	if f.Line > 0 {
+		s.appendKey("line")
+		s.buf = append(s.buf, '"')
+		s.buf = strconv.AppendInt(s.buf, int64(f.Line), 10)
+		s.buf = append(s.buf, '"')
+	}
+	if f.Function != "" {
+		s.appendKey("function")
+		s.appendString(f.Function)
+	}
+	s.closeGroup()
+}
+
+func (s *encodeState) appendValue(v slog.Value) {
+	defer func() {
+		// Do the same as slog.JSONHandler, which in turn follows the
+		// fmt package.
+		if r := recover(); r != nil {
+			if v := reflect.ValueOf(v.Any()); v.Kind() == reflect.Pointer && v.IsNil() {
+				s.appendString("<nil>")
+				return
+			}
+			s.appendString(fmt.Sprintf("!PANIC: %v", r))
+		}
+	}()
+	switch v.Kind() {
+	case slog.KindString:
+		s.appendText(v.String())
+	case slog.KindInt64:
+		s.buf = strconv.AppendInt(s.buf, v.Int64(), 10)
+	case slog.KindUint64:
+		s.buf = strconv.AppendUint(s.buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		s.appendFloat(v.Float64())
+	case slog.KindBool:
+		s.buf = strconv.AppendBool(s.buf, v.Bool())
+	case slog.KindDuration:
+		// Like encoding/json.
+		s.buf = strconv.AppendInt(s.buf, int64(v.Duration()), 10)
+	case slog.KindTime:
+		s.appendTime(v.Time())
+	default:
+		a := v.Any()
+		_, jm := a.(json.Marshaler)
+		if err, ok := a.(error); ok && !jm {
+			s.appendText(err.Error())
+		} else {
+			s.appendJSON(a)
+		}
+	}
+}
+
+func (s *encodeState) appendTime(t time.Time) {
+	if y := t.Year(); y < 0 || y >= 10000 {
+		s.appendError(errors.New("time.Time year outside of range [0,9999]"))
+		return
+	}
+	s.buf = s.enc.timeFormat.appendTime(s.buf, t)
+}
+
+// appendFloat formats f in the same way as encoding/json.
+func (s *encodeState) appendFloat(f float64) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		// Let encoding/json produce the error.
+		s.appendJSON(f)
+		return
+	}
+	format := byte('f')
+	if a := math.Abs(f); a != 0 && (a < 1e-6 || a >= 1e21) {
+		format = 'e'
+	}
+	b := strconv.AppendFloat(s.buf, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	s.buf = b
+}
+
+func (s *encodeState) appendJSON(v any) {
+	j := jsonEncoderPool.Get().(*jsonEncoder)
+	defer j.free()
+	if err := j.enc.Encode(v); err != nil {
+		s.appendError(err)
+		return
+	}
+	b := j.buf.Bytes()
+	b = b[:len(b)-1] // remove final newline
+	if s.limit > 0 && len(b) > s.limit {
+		// We can’t truncate JSON values in a meaningful way, so
+		// write them as truncated strings instead.
+		s.appendText(string(b))
+		return
+	}
+	s.buf = append(s.buf, b...)
+}
+
+func (s *encodeState) appendError(err error) {
+	s.appendString(fmt.Sprintf("!ERROR:%v", err))
+}
+
+// frame returns the stack frame for pc, with Options.TrimSourcePrefix removed
+// from the file name.
+func (e *encoder) frame(pc uintptr) runtime.Frame {
+	f := frame(pc)
+	f.File = strings.TrimPrefix(f.File, e.trimSource)
+	return f
+}
+
+// frame returns the stack frame for pc.  It caches the frames because
+// runtime.CallersFrames allocates, and there are only so many places in a
+// program that log anything.
+func frame(pc uintptr) runtime.Frame {
+	frames.mu.RLock()
+	f, ok := frames.m[pc]
+	frames.mu.RUnlock()
+	if ok {
+		return f
+	}
+	f, _ = runtime.CallersFrames([]uintptr{pc}).Next()
+	// Don’t keep references to internal runtime data.
+	f = runtime.Frame{Function: f.Function, File: f.File, Line: f.Line}
+	frames.mu.Lock()
+	frames.m[pc] = f
+	frames.mu.Unlock()
+	return f
+}
+
+var frames = struct {
+	mu sync.RWMutex
+	m  map[uintptr]runtime.Frame
+}{m: make(map[uintptr]runtime.Frame)}
+
+// sourceGroup returns the same group as slog.Source.group.
+func sourceGroup(s *slog.Source) slog.Value {
+	var attrs []slog.Attr
+	if s.Function != "" {
+		attrs = append(attrs, slog.String("function", s.Function))
+	}
+	if s.File != "" {
+		attrs = append(attrs, slog.String("file", s.File))
+	}
+	if s.Line != 0 {
+		attrs = append(attrs, slog.Int("line", s.Line))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// jsonEncoder encodes arbitrary values.  We use a json.Encoder instead of
+// json.Marshal so that we can turn off HTML escaping, like slog.JSONHandler.
+type jsonEncoder struct {
+	buf bytesBuffer
+	enc *json.Encoder
+}
+
+var jsonEncoderPool = sync.Pool{
+	New: func() any {
+		j := new(jsonEncoder)
+		j.enc = json.NewEncoder(&j.buf)
+		j.enc.SetEscapeHTML(false)
+		return j
+	},
+}
+
+func (j *jsonEncoder) free() {
+	// Don’t keep large buffers around.
+	if cap(j.buf) > maxBufferSize {
+		return
+	}
+	j.buf = j.buf[:0]
+	jsonEncoderPool.Put(j)
+}
+
+// bytesBuffer is a minimal [io.Writer] that appends to a byte slice.
+type bytesBuffer []byte
+
+func (b *bytesBuffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+func (b *bytesBuffer) Bytes() []byte { return *b }
+
+// maxBufferSize is the capacity up to which we return buffers to their pools.
+const maxBufferSize = 16 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// attrsPool contains attribute slices for Handler.Handle.
+var attrsPool = sync.Pool{
+	New: func() any {
+		a := make([]slog.Attr, 0, 16)
+		return &a
+	},
+}
+
+func putAttrs(p *[]slog.Attr) {
+	// Don’t keep large slices or references to attribute values around.
+	if cap(*p) > 256 {
+		return
+	}
+	clear((*p)[:cap(*p)])
+	*p = (*p)[:0]
+	attrsPool.Put(p)
+}
+
+// appendEscaped escapes str for JSON and appends it to buf.  It doesn’t add
+// quotation marks.  The escaping is the same as that of slog.JSONHandler,
+// i.e., encoding/json without HTML escaping.
+func appendEscaped(buf []byte, str string) []byte {
+	start := 0
+	for i := 0; i < len(str); {
+		if b := str[i]; b < utf8.RuneSelf {
+			if b >= ' ' && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			buf = append(buf, str[start:i]...)
+			switch b {
+			case '\\', '"':
+				buf = append(buf, '\\', b)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(str[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf = append(buf, str[start:i]...)
+			// Recent versions of encoding/json write the
+			// replacement character unescaped.
+			buf = append(buf, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid in JSON strings, but not in
+		// JavaScript, so encoding/json escapes them.
+		if c == '\u2028' || c == '\u2029' {
+			buf = append(buf, str[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	return append(buf, str[start:]...)
+}
+
+const hexDigits = "0123456789abcdef"
@@ -0,0 +1,300 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is a log entry as written by a [Handler], with the [special fields]
+// decoded.  Use [DecodeEntries] or [ParseEntry] to obtain entries, for
+// example in tests, log-replay tools, or for post-processing.
+//
+// [special fields]: https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+type Entry struct {
+	Time     time.Time
+	Severity string
+	Message  string
+
+	// Full trace name of the form “projects/PROJECT/traces/TRACE”, span
+	// ID, and sampling decision.  The trace and the span ID are empty if
+	// the entry doesn’t belong to a trace.
+	Trace        string
+	SpanID       string
+	TraceSampled bool
+
+	// Special fields that are nil or empty if the entry doesn’t have
+	// them.
+	Labels         map[string]string
+	HTTPRequest    *HTTPRequest
+	SourceLocation *SourceLocation
+	Operation      *Operation
+
+	// All other fields, as decoded by [json.Unmarshal] into an any
+	// value.  Groups are maps, and numbers are float64 values.
+	Attrs map[string]any
+
+	// The entire entry as written by the handler.
+	JSON json.RawMessage
+}
+
+// HTTPRequest describes the HTTP request of an [Entry].  See [HttpRequest] for
+// the meaning of the fields.
+//
+// [HttpRequest]: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+type HTTPRequest struct {
+	RequestMethod string
+	RequestURL    string
+	RequestSize   int64
+	Status        int
+	ResponseSize  int64
+	UserAgent     string
+	RemoteIP      string
+	ServerIP      string
+	Referer       string
+	Latency       time.Duration
+	Protocol      string
+}
+
+// SourceLocation is the source location of an [Entry].
+type SourceLocation struct {
+	File     string
+	Line     int
+	Function string
+}
+
+// Operation describes the operation of an [Entry], see [WithOperation].
+type Operation struct {
+	ID       string
+	Producer string
+	First    bool
+	Last     bool
+}
+
+// Attr returns the value of the attribute with the given key, which is nested
+// in the given groups.  Values are as described for [Entry.Attrs].
+func (e Entry) Attr(path ...string) (any, bool) {
+	var v any = e.Attrs
+	for _, k := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return v, len(path) > 0
+}
+
+// DecodeEntries reads log entries in JSON format, one entry per line, as
+// written by a [Handler].  It skips empty lines and returns an error if a line
+// isn’t a valid entry.
+func DecodeEntries(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	s := bufio.NewScanner(r)
+	// Cloud Logging entries can be up to 256 KiB, so the default
+	// buffer size isn’t enough.
+	s.Buffer(nil, 1<<20)
+	for i := 0; s.Scan(); i++ {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		e, err := ParseEntry(line)
+		if err != nil {
+			return entries, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
+
+// ParseEntry parses a single log entry in JSON format as written by a
+// [Handler].  Special fields with the wrong type result in an error.
+func ParseEntry(b []byte) (Entry, error) {
+	d := entryDecoder{e: Entry{JSON: bytes.Clone(b)}}
+	if err := json.Unmarshal(b, &d.e.Attrs); err != nil {
+		return Entry{}, err
+	}
+	// Decode the special fields again without losing the precision of
+	// large integers such as TimeFormatUnixNano timestamps.
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&d.special); err != nil {
+		return Entry{}, err
+	}
+	if d.e.Attrs == nil {
+		return Entry{}, errors.New("aelog: log entry isn’t a JSON object")
+	}
+	d.decode()
+	if d.err != nil {
+		return Entry{}, d.err
+	}
+	return d.e, nil
+}
+
+// entryDecoder decodes the special fields of an entry.  It records the first
+// error.
+type entryDecoder struct {
+	e   Entry
+	err error
+
+	// All fields of the entry, with numbers decoded as json.Number.
+	special map[string]any
+}
+
+func (d *entryDecoder) decode() {
+	e := &d.e
+	switch v := d.take(TimeKey).(type) {
+	case nil:
+	case json.Number:
+		// TimeFormatUnixNano.
+		n, err := v.Int64()
+		d.fail(TimeKey, err)
+		e.Time = time.Unix(0, n).UTC()
+	default:
+		if s := d.string(v, TimeKey); s != "" {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			d.fail(TimeKey, err)
+			e.Time = t
+		}
+	}
+	e.Severity = d.string(d.take(SeverityKey), SeverityKey)
+	e.Message = d.string(d.take(MessageKey), MessageKey)
+	e.Trace = d.string(d.take("logging.googleapis.com/trace"), "logging.googleapis.com/trace")
+	e.SpanID = d.string(d.take("logging.googleapis.com/spanId"), "logging.googleapis.com/spanId")
+	e.TraceSampled = d.bool(d.take("logging.googleapis.com/trace_sampled"), "logging.googleapis.com/trace_sampled")
+	if m := d.object(d.take(LabelsKey), LabelsKey); m != nil {
+		e.Labels = make(map[string]string, len(m))
+		for k, v := range m {
+			e.Labels[k] = d.string(v, LabelsKey+"."+k)
+		}
+	}
+	if m := d.object(d.take("httpRequest"), "httpRequest"); m != nil {
+		field := func(k string) (any, string) { return m[k], "httpRequest." + k }
+		e.HTTPRequest = &HTTPRequest{
+			RequestMethod: d.string(field("requestMethod")),
+			RequestURL:    d.string(field("requestUrl")),
+			RequestSize:   d.int64(field("requestSize")),
+			Status:        int(d.int64(field("status"))),
+			ResponseSize:  d.int64(field("responseSize")),
+			UserAgent:     d.string(field("userAgent")),
+			RemoteIP:      d.string(field("remoteIp")),
+			ServerIP:      d.string(field("serverIp")),
+			Referer:       d.string(field("referer")),
+			Latency:       d.duration(field("latency")),
+			Protocol:      d.string(field("protocol")),
+		}
+	}
+	if m := d.object(d.take(SourceLocationKey), SourceLocationKey); m != nil {
+		field := func(k string) (any, string) { return m[k], SourceLocationKey + "." + k }
+		e.SourceLocation = &SourceLocation{
+			File:     d.string(field("file")),
+			Line:     int(d.int64(field("line"))),
+			Function: d.string(field("function")),
+		}
+	}
+	if m := d.object(d.take(OperationKey), OperationKey); m != nil {
+		field := func(k string) (any, string) { return m[k], OperationKey + "." + k }
+		e.Operation = &Operation{
+			ID:       d.string(field("id")),
+			Producer: d.string(field("producer")),
+			First:    d.bool(field("first")),
+			Last:     d.bool(field("last")),
+		}
+	}
+}
+
+// take removes the field with the given key from the attributes and returns
+// its value, with numbers represented as json.Number.
+func (d *entryDecoder) take(key string) any {
+	delete(d.e.Attrs, key)
+	return d.special[key]
+}
+
+func (d *entryDecoder) fail(field string, err error) {
+	if err != nil && d.err == nil {
+		d.err = fmt.Errorf("aelog: field %s: %w", field, err)
+	}
+}
+
+func (d *entryDecoder) string(v any, field string) string {
+	s, ok := v.(string)
+	if v != nil && !ok {
+		d.fail(field, fmt.Errorf("got %T, want string", v))
+	}
+	return s
+}
+
+func (d *entryDecoder) bool(v any, field string) bool {
+	b, ok := v.(bool)
+	if v != nil && !ok {
+		d.fail(field, fmt.Errorf("got %T, want bool", v))
+	}
+	return b
+}
+
+func (d *entryDecoder) object(v any, field string) map[string]any {
+	m, ok := v.(map[string]any)
+	if v != nil && !ok {
+		d.fail(field, fmt.Errorf("got %T, want object", v))
+	}
+	return m
+}
+
+// int64 decodes an integer.  The JSON representation of protocol buffers
+// uses strings for 64-bit integers, but we also accept numbers.
+func (d *entryDecoder) int64(v any, field string) int64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case json.Number:
+		n, err := v.Int64()
+		d.fail(field, err)
+		return n
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		d.fail(field, err)
+		return n
+	default:
+		d.fail(field, fmt.Errorf("got %T, want integer", v))
+		return 0
+	}
+}
+
+// duration decodes a duration in the JSON representation of protocol
+// buffers, e.g. “1.5s”.
+func (d *entryDecoder) duration(v any, field string) time.Duration {
+	s := d.string(v, field)
+	if s == "" {
+		return 0
+	}
+	if !strings.HasSuffix(s, "s") {
+		d.fail(field, fmt.Errorf("invalid duration %q", s))
+		return 0
+	}
+	t, err := time.ParseDuration(s)
+	d.fail(field, err)
+	return t
+}
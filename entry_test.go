@@ -0,0 +1,146 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/phst/aelog"
+)
+
+func TestDecodeEntries(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{AddSource: true}, &aelog.Options{
+		ProjectID: "test-project",
+		Labels:    map[string]string{"env": "test"},
+	}))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		ctx := aelog.WithOperation(r.Context(), "op", "prod")
+		log.With("a", 1).WithGroup("g").InfoContext(ctx, "info", "b", "two", aelog.OperationFirst())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "abc/123;o=1")
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+	log.ErrorContext(context.Background(), "error")
+
+	got, err := aelog.DecodeEntries(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []aelog.Entry{
+		{
+			Severity:     "INFO",
+			Message:      "info",
+			Trace:        "projects/test-project/traces/abc",
+			SpanID:       "123",
+			TraceSampled: true,
+			Labels:       map[string]string{"env": "test"},
+			HTTPRequest: &aelog.HTTPRequest{
+				RequestMethod: "GET",
+				RequestURL:    "/path",
+				RemoteIP:      "192.0.2.1:1234",
+				Protocol:      "HTTP/1.1",
+			},
+			Operation: &aelog.Operation{ID: "op", Producer: "prod", First: true},
+			Attrs:     map[string]any{"a": 1.0, "g": map[string]any{"b": "two"}},
+		},
+		{
+			Severity: "ERROR",
+			Message:  "error",
+			Labels:   map[string]string{"env": "test"},
+			Attrs:    map[string]any{},
+		},
+	}
+	opts := cmp.Options{
+		cmpopts.IgnoreFields(aelog.Entry{}, "Time", "SourceLocation", "JSON"),
+		cmpopts.EquateEmpty(),
+	}
+	if diff := cmp.Diff(got, want, opts); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	for _, e := range got {
+		if e.Time.IsZero() {
+			t.Errorf("entry %q has no time", e.Message)
+		}
+		if e.SourceLocation == nil || !strings.HasSuffix(e.SourceLocation.File, "entry_test.go") || e.SourceLocation.Line == 0 {
+			t.Errorf("entry %q has wrong source location: %+v", e.Message, e.SourceLocation)
+		}
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	for _, tc := range []struct {
+		name, line string
+		want       aelog.Entry
+		wantErr    bool
+	}{
+		{
+			name: "request",
+			line: `{"severity":"INFO","httpRequest":{"status":404,"requestSize":"12","responseSize":"345","latency":"1.5s"}}`,
+			want: aelog.Entry{
+				Severity: "INFO",
+				HTTPRequest: &aelog.HTTPRequest{
+					Status:       404,
+					RequestSize:  12,
+					ResponseSize: 345,
+					Latency:      1500 * time.Millisecond,
+				},
+				Attrs: map[string]any{},
+			},
+		},
+		{
+			name: "source",
+			line: `{"logging.googleapis.com/sourceLocation":{"file":"main.go","line":"12","function":"main.main"},"x":null}`,
+			want: aelog.Entry{
+				SourceLocation: &aelog.SourceLocation{File: "main.go", Line: 12, Function: "main.main"},
+				Attrs:          map[string]any{"x": nil},
+			},
+		},
+		{name: "not an object", line: `[]`, wantErr: true},
+		{name: "invalid JSON", line: `{`, wantErr: true},
+		{name: "wrong type", line: `{"severity":1}`, wantErr: true},
+		{name: "invalid time", line: `{"time":"yesterday"}`, wantErr: true},
+		{name: "invalid latency", line: `{"httpRequest":{"latency":"1h"}}`, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := aelog.ParseEntry([]byte(tc.line))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ParseEntry(%s) = %+v, want error", tc.line, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEntry(%s): %s", tc.line, err)
+			}
+			if diff := cmp.Diff(got, tc.want, cmpopts.IgnoreFields(aelog.Entry{}, "JSON")); diff != "" {
+				t.Error("-got +want", diff)
+			}
+			if string(got.JSON) != tc.line {
+				t.Errorf("JSON = %s, want %s", got.JSON, tc.line)
+			}
+		})
+	}
+}
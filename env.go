@@ -0,0 +1,67 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import "os"
+
+// envLabel is an environment variable that NewHandler adds as a label if it’s
+// set.
+type envLabel struct{ env, label string }
+
+// envLabels lists environment variables that NewHandler adds as labels if they
+// are set, together with the label keys.  See [Options.EnvironmentFields].
+var envLabels = []envLabel{
+	// App Engine, see
+	// https://cloud.google.com/appengine/docs/standard/go/runtime#environment_variables.
+	{"GAE_INSTANCE", "instance_id"},
+	{"GAE_DEPLOYMENT_ID", "deployment_id"},
+	{"GAE_RUNTIME", "runtime"},
+	// Cloud Run, see
+	// https://cloud.google.com/run/docs/container-contract#env-vars.
+	// The label keys match the resource labels of the
+	// cloud_run_revision monitored resource.
+	{"K_SERVICE", "service_name"},
+	{"K_REVISION", "revision_name"},
+	{"K_CONFIGURATION", "configuration_name"},
+}
+
+// kubernetesEnvLabels lists environment variables that NewHandler adds as
+// labels if [Options.KubernetesLabels] is set.  Kubernetes doesn’t set them
+// by itself, but they are the conventional names for exposing pod fields
+// through the [Downward API].  The label keys match the resource labels of
+// the k8s_container monitored resource.
+//
+// [Downward API]: https://kubernetes.io/docs/concepts/workloads/pods/downward-api/
+var kubernetesEnvLabels = []envLabel{
+	{"POD_NAME", "pod_name"},
+	{"POD_NAMESPACE", "namespace_name"},
+	{"NODE_NAME", "node_name"},
+	{"CONTAINER_NAME", "container_name"},
+}
+
+// detectLabels returns labels for the environment variables in vars that are
+// set.  It returns nil if none are set.
+func detectLabels(vars []envLabel) map[string]string {
+	var labels map[string]string
+	for _, v := range vars {
+		if s := os.Getenv(v.env); s != "" {
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[v.label] = s
+		}
+	}
+	return labels
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// ErrorKey is the attribute key used by [Error].
+const ErrorKey = "error"
+
+// Error returns an attribute with the key [ErrorKey] that renders the given
+// error as a group with the following members:
+//
+//   - “message”: the error message
+//   - “type”: the dynamic Go type of the error, as formatted by the %T verb
+//   - “causes”: the errors that err wraps, as returned by an Unwrap method,
+//     in the same structure; omitted if err doesn’t wrap other errors
+//
+// This retains the structure of wrapped errors and errors created by
+// [errors.Join], which a plain string attribute would lose.  If err is nil,
+// the attribute value is nil.
+func Error(err error) slog.Attr {
+	if err == nil {
+		return slog.Any(ErrorKey, nil)
+	}
+	return slog.Any(ErrorKey, errorValue{err})
+}
+
+// errorValue is the value of an attribute returned by Error.  Implementing
+// slog.LogValuer defers the work until a handler actually needs the value.
+type errorValue struct{ err error }
+
+func (v errorValue) LogValue() slog.Value {
+	info := newErrorInfo(v.err)
+	attrs := []slog.Attr{
+		slog.String("message", info.Message),
+		slog.String("type", info.Type),
+	}
+	if len(info.Causes) > 0 {
+		attrs = append(attrs, slog.Any("causes", info.Causes))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// errorInfo is the JSON representation of an error within the “causes”
+// member.  slog groups can’t represent arrays, so nested errors use
+// encoding/json directly.
+type errorInfo struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Causes  []errorInfo `json:"causes,omitempty"`
+}
+
+func newErrorInfo(err error) errorInfo {
+	info := errorInfo{Message: err.Error(), Type: fmt.Sprintf("%T", err)}
+	var causes []error
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if c := e.Unwrap(); c != nil {
+			causes = []error{c}
+		}
+	case interface{ Unwrap() []error }:
+		causes = e.Unwrap()
+	}
+	for _, c := range causes {
+		if c != nil {
+			info.Causes = append(info.Causes, newErrorInfo(c))
+		}
+	}
+	return info
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestError(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, nil))
+	base := errors.New("base")
+	joined := errors.Join(base, fmt.Errorf("wrapped: %w", base))
+	log.Error("error", aelog.Error(fmt.Errorf("outer: %w", joined)))
+	log.Error("nil", aelog.Error(nil))
+
+	got := parseRecords(t, buf)
+	leaf := map[string]any{"message": "base", "type": "*errors.errorString"}
+	want := []map[string]any{
+		{
+			"severity": "ERROR",
+			"message":  "error",
+			"error": map[string]any{
+				"message": "outer: base\nwrapped: base",
+				"type":    "*fmt.wrapError",
+				"causes": []any{map[string]any{
+					"message": "base\nwrapped: base",
+					"type":    "*errors.joinError",
+					"causes": []any{
+						leaf,
+						map[string]any{
+							"message": "wrapped: base",
+							"type":    "*fmt.wrapError",
+							"causes":  []any{leaf},
+						},
+					},
+				}},
+			},
+		},
+		{"severity": "ERROR", "message": "nil", "error": nil},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// ErrorEventType is the value of the “@type” field that marks log entries as
+// [ReportedErrorEvent] payloads for Error Reporting.  See
+// [Options.ErrorReporting].
+//
+// [ReportedErrorEvent]: https://cloud.google.com/error-reporting/docs/formatting-error-messages#reported-error-example
+const ErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// errorContextFields maps keys of the HttpRequest structure in log entries to
+// the corresponding keys of the HttpRequestContext structure in error events.
+// See
+// https://cloud.google.com/error-reporting/reference/rest/v1beta1/ErrorContext#HttpRequestContext.
+var errorContextFields = map[string]string{
+	"requestMethod": "method",
+	"requestUrl":    "url",
+	"userAgent":     "userAgent",
+	"referer":       "referrer",
+	"remoteIp":      "remoteIp",
+	"status":        "responseStatusCode",
+}
+
+// errorReportingAttrs returns the attributes that turn a record into an
+// error event.  pc is the program counter of the logging call, or zero if
+// unknown.  The function removes trim from the file name.
+func errorReportingAttrs(ctx context.Context, pc uintptr, trim string) []slog.Attr {
+	// https://cloud.google.com/error-reporting/reference/rest/v1beta1/ErrorContext
+	var errCtx []slog.Attr
+	if req, ok := HTTPRequestFromContext(ctx); ok {
+		var attrs []slog.Attr
+		for _, a := range req.Group() {
+			if k, ok := errorContextFields[a.Key]; ok {
+				attrs = append(attrs, slog.Attr{Key: k, Value: a.Value})
+			}
+		}
+		errCtx = append(errCtx, slog.Attr{Key: "httpRequest", Value: slog.GroupValue(attrs...)})
+	}
+	if pc != 0 {
+		f := frame(pc)
+		errCtx = append(errCtx, slog.Group(
+			"reportLocation",
+			slog.String("filePath", strings.TrimPrefix(f.File, trim)),
+			slog.Int("lineNumber", f.Line),
+			slog.String("functionName", f.Function),
+		))
+	}
+	attrs := []slog.Attr{slog.String("@type", ErrorEventType)}
+	if len(errCtx) > 0 {
+		attrs = append(attrs, slog.Attr{Key: "context", Value: slog.GroupValue(errCtx...)})
+	}
+	return attrs
+}
@@ -0,0 +1,24 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+// ResetMetadataCache forgets the project IDs that metadata servers have
+// returned, so that tests can start their own metadata servers even if a
+// previous test used the same address.
+func ResetMetadataCache() {
+	metadataProjects.mu.Lock()
+	defer metadataProjects.mu.Unlock()
+	metadataProjects.m = nil
+}
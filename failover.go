@@ -0,0 +1,114 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// NewFailoverWriter creates a new [FailoverWriter] that writes to primary and
+// fails over to the secondaries in order.  Passing nil options has the same
+// effect as passing a pointer to a zero struct.
+func NewFailoverWriter(primary io.Writer, secondaries []io.Writer, opts *FailoverOptions) *FailoverWriter {
+	if opts == nil {
+		opts = new(FailoverOptions)
+	}
+	ws := append([]io.Writer{primary}, secondaries...)
+	return &FailoverWriter{ws: ws, retries: max(opts.Retries, 0), backoff: opts.Backoff}
+}
+
+// FailoverWriter is an [io.Writer] that writes to a primary writer and, if
+// that fails, to one or more secondary writers in order, for deployments where
+// losing log entries isn’t acceptable.  Optionally, it retries failed writes
+// with exponential backoff before moving on to the next writer.  Retries only
+// write the data that the failed write didn’t write, so entries stay intact
+// as long as the underlying writer reports partial writes correctly.  A
+// [Handler] calls Write once per entry, so the secondaries receive complete
+// entries; however, a partially written entry remains in the output of the
+// writer that failed.  FailoverWriter is safe for concurrent use; writes are serialized.
+//
+// Use [NewFailoverWriter] to create FailoverWriter objects.
+type FailoverWriter struct {
+	ws      []io.Writer
+	retries int
+	backoff time.Duration
+
+	mu sync.Mutex
+}
+
+// FailoverOptions contains options for a [FailoverWriter].
+type FailoverOptions struct {
+	// Number of times to retry a failed write to each writer before
+	// failing over to the next one.  If zero, don’t retry.
+	Retries int
+
+	// Time to wait before the first retry.  The delay doubles for each
+	// further retry.  If zero, retry immediately.
+	Backoff time.Duration
+}
+
+// Write implements [io.Writer.Write].  It returns an error only if writing to
+// all writers fails; the error then contains the errors of all writers.
+func (w *FailoverWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var errs []error
+	for _, d := range w.ws {
+		err := w.write(d, b)
+		if err == nil {
+			return len(b), nil
+		}
+		errs = append(errs, err)
+	}
+	return 0, errors.Join(errs...)
+}
+
+// write writes b to d, retrying as configured.  w.mu must be locked.
+func (w *FailoverWriter) write(d io.Writer, b []byte) error {
+	delay := w.backoff
+	for i := 0; ; i++ {
+		n, err := d.Write(b)
+		if err == nil {
+			return nil
+		}
+		if i >= w.retries {
+			return err
+		}
+		b = b[n:]
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Flush flushes all underlying writers that have a method
+//
+//	Flush() error
+//
+// such as [BufferedWriter].  It returns the errors of all failed writers
+// joined using [errors.Join].  [Handler.Flush] calls Flush automatically.
+func (w *FailoverWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var errs []error
+	for _, d := range w.ws {
+		if f, ok := d.(flusher); ok {
+			errs = append(errs, f.Flush())
+		}
+	}
+	return errors.Join(errs...)
+}
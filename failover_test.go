@@ -0,0 +1,79 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestFailoverWriter(t *testing.T) {
+	primary := &flakyWriter{failures: 1, partial: 5}
+	secondary := new(bytes.Buffer)
+	h := aelog.NewHandler(aelog.NewFailoverWriter(primary, []io.Writer{failingWriter{}, secondary}, &aelog.FailoverOptions{
+		Retries: 1,
+		Backoff: time.Millisecond,
+	}), nil, nil)
+	log := slog.New(h)
+	log.Info("first")  // first attempt writes 5 bytes, retry fails
+	log.Info("second") // succeeds
+	primary.failures, primary.partial = 10, 0
+	log.Info("third") // goes to the secondary
+
+	want := []map[string]any{
+		{"severity": "INFO", "message": "first"},
+		{"severity": "INFO", "message": "second"},
+	}
+	if diff := cmp.Diff(parseRecords(t, &primary.buf), want, ignoreTime); diff != "" {
+		t.Error("primary: -got +want", diff)
+	}
+	want = []map[string]any{{"severity": "INFO", "message": "third"}}
+	if diff := cmp.Diff(parseRecords(t, secondary), want, ignoreTime); diff != "" {
+		t.Error("secondary: -got +want", diff)
+	}
+}
+
+func TestFailoverWriter_allFail(t *testing.T) {
+	w := aelog.NewFailoverWriter(failingWriter{}, []io.Writer{failingWriter{}}, nil)
+	if _, err := w.Write([]byte("foo\n")); err == nil {
+		t.Error("Write succeeded even though all writers failed")
+	}
+}
+
+// flakyWriter fails the given number of times, writing at most partial bytes
+// each time.
+type flakyWriter struct {
+	buf      bytes.Buffer
+	failures int
+	partial  int
+}
+
+func (w *flakyWriter) Write(b []byte) (int, error) {
+	if w.failures > 0 {
+		w.failures--
+		n := min(len(b), w.partial)
+		w.buf.Write(b[:n])
+		return n, errors.New("flaky")
+	}
+	return w.buf.Write(b)
+}
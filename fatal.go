@@ -0,0 +1,63 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// Fatal logs a message at [LevelEmergency] using the default logger, flushes
+// the default handler, and exits the process with status 1.  The arguments
+// are interpreted as in [slog.Logger.Log].  Flushing works for any handler
+// that has a method
+//
+//	Flush() error
+//
+// and ensures that the last log record of a crashing process doesn’t get lost
+// in a buffer.
+func Fatal(ctx context.Context, msg string, args ...any) {
+	exit(ctx, LevelEmergency, msg, args...)
+}
+
+// Alert is like [Fatal], but logs at [LevelAlert].
+func Alert(ctx context.Context, msg string, args ...any) {
+	exit(ctx, LevelAlert, msg, args...)
+}
+
+// Critical is like [Fatal], but logs at [LevelCritical].
+func Critical(ctx context.Context, msg string, args ...any) {
+	exit(ctx, LevelCritical, msg, args...)
+}
+
+func exit(ctx context.Context, level slog.Level, msg string, args ...any) {
+	// Skip exit and the exported wrapper function.
+	logAt(ctx, nil, level, 2, msg, args...)
+	flush(slog.Default().Handler())
+	os.Exit(1)
+}
+
+// flusher is implemented by handlers and writers that buffer their output.
+type flusher interface {
+	Flush() error
+}
+
+func flush(h slog.Handler) {
+	if f, ok := h.(flusher); ok {
+		// There’s nothing we can do about errors here.
+		_ = f.Flush()
+	}
+}
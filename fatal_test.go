@@ -0,0 +1,71 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestFatal(t *testing.T) {
+	if os.Getenv("AELOG_TEST_FATAL") == "1" {
+		// We’re running in the subprocess.  Buffer all output so that
+		// we can check that Fatal flushes it.
+		h := &bufferingHandler{buf: new(bytes.Buffer)}
+		h.Handler = aelog.NewHandler(h.buf, nil, nil)
+		slog.SetDefault(slog.New(h))
+		aelog.Fatal(context.Background(), "fatal error", "attr", 123)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatal$")
+	cmd.Env = append(os.Environ(), "AELOG_TEST_FATAL=1")
+	stdout := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("subprocess didn’t exit with status 1: %v", err)
+	}
+
+	got := parseRecords(t, stdout)
+	want := []map[string]any{{
+		"severity": "EMERGENCY",
+		"message":  "fatal error",
+		"attr":     123.0,
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+type bufferingHandler struct {
+	*aelog.Handler
+	buf *bytes.Buffer
+}
+
+func (h *bufferingHandler) Flush() error {
+	_, err := h.buf.WriteTo(os.Stdout)
+	return err
+}
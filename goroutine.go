@@ -0,0 +1,49 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"fmt"
+)
+
+// Go calls f in a new goroutine.  The context passed to f is detached from
+// the cancellation of ctx (see [context.WithoutCancel]), but retains its
+// values, so that records logged with it are still correlated with the
+// incoming request (see [Middleware]) even after the request has finished.
+// If f panics, Go logs the panic value and a stack trace at [LevelCritical]
+// using the default logger and recovers from the panic.  Use Go instead of a
+// bare go statement in HTTP handlers:
+//
+//	aelog.Go(r.Context(), func(ctx context.Context) {
+//		// …
+//	})
+func Go(ctx context.Context, f func(ctx context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer recoverPanic(ctx)
+		f(ctx)
+	}()
+}
+
+// recoverPanic logs a panic in a goroutine started by [Go].  It must be
+// called directly as a deferred function.
+func recoverPanic(ctx context.Context) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	logPC(ctx, nil, LevelCritical, panicPC(), fmt.Sprint("panic: ", v), StackTraceKey, panicStack(v))
+}
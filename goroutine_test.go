@@ -0,0 +1,70 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestGo(t *testing.T) {
+	written := make(chan []byte, 1)
+	// Restoring the default logger doesn’t restore the output of the log
+	// package.
+	defer log.SetOutput(log.Writer())
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(aelog.NewHandler(chanWriter(written), nil, &aelog.Options{ProjectID: "test"})))
+
+	ctx, cancel := context.WithCancel(aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "abc"}))
+	cancel()
+	aelog.Go(ctx, func(ctx context.Context) {
+		if err := ctx.Err(); err != nil {
+			t.Errorf("context error: %v", err)
+		}
+		panic("boom")
+	})
+
+	got := parseRecords(t, bytes.NewReader(<-written))
+	if len(got) != 1 {
+		t.Fatalf("got %d records, want one", len(got))
+	}
+	if stack, _ := got[0][aelog.StackTraceKey].(string); !strings.HasPrefix(stack, "panic: boom\n") {
+		t.Errorf("stack trace: got %q", stack)
+	}
+	want := []map[string]any{{
+		"severity":                     "CRITICAL",
+		"message":                      "panic: boom",
+		"logging.googleapis.com/trace": "projects/test/traces/abc",
+	}}
+	if diff := cmp.Diff(got, want, ignoreFields(aelog.TimeKey, aelog.StackTraceKey)); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+// chanWriter sends each write to the channel.
+type chanWriter chan<- []byte
+
+func (w chanWriter) Write(b []byte) (int, error) {
+	w <- bytes.Clone(b)
+	return len(b), nil
+}
@@ -1,4 +1,4 @@
-// Copyright 2023, 2024 Google LLC
+// Copyright 2023, 2024, 2026 Google LLC
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -16,11 +16,18 @@ package aelog
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"reflect"
+	rtrace "runtime/trace"
 	"slices"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // NewHandler creates a new [Handler].  The handler will write to the given
@@ -29,10 +36,33 @@ import (
 // any of the options has the same effect as passing a pointer to a zero
 // struct.
 //
+// The handler calls [slog.HandlerOptions.ReplaceAttr] for every non-group
+// attribute, including attributes nested in groups, with the full group path.
+// This works the same whether the groups were created using
+// [slog.Logger.WithGroup] or [slog.Group].  At the top level, ReplaceAttr sees
+// the special keys such as [SeverityKey] instead of the standard [slog] keys.
+//
 // If [Options] doesn’t contain a project ID, NewHandler attempts to
 // auto-detect the current project; this typically works when running in
-// production.  If no project can be detected, tracing information won’t be
-// filled out.
+// production.  It consults the environment variables GOOGLE_CLOUD_PROJECT,
+// GCLOUD_PROJECT, GCP_PROJECT, and GOOGLE_CLOUD_QUOTA_PROJECT, in that order,
+// and uses the first nonempty one.  If none of them is set, the handler asks
+// the [metadata server], which is available on Compute Engine, Cloud Run, and
+// GKE.  It only does so once a record needs the project ID, that is, the first
+// time it writes a record that belongs to a trace, and it only asks once per
+// process with a short timeout.  Outside of Google Cloud, this still delays
+// that first record; set [Options.ProjectID] or one of the environment
+// variables to avoid that.  The environment variable GCE_METADATA_HOST
+// overrides the address of the metadata server.  If no project can be
+// detected, tracing information won’t be filled out.
+//
+// If neither [Options.LevelVar] nor [slog.HandlerOptions.Level] is set,
+// NewHandler creates a new [slog.LevelVar] for the minimum level.  It
+// initializes the level from the environment variable LOG_LEVEL if that
+// contains a level name accepted by [ParseLevel], and to [LevelInfo]
+// otherwise.  Use [Handler.LevelVar] to change the level at runtime.
+//
+// [metadata server]: https://cloud.google.com/compute/docs/metadata/overview
 func NewHandler(w io.Writer, basicOpts *slog.HandlerOptions, extOpts *Options) *Handler {
 	if basicOpts == nil {
 		basicOpts = new(slog.HandlerOptions)
@@ -40,44 +70,214 @@ func NewHandler(w io.Writer, basicOpts *slog.HandlerOptions, extOpts *Options) *
 	if extOpts == nil {
 		extOpts = new(Options)
 	}
-	repl := basicOpts.ReplaceAttr
 	projectID := extOpts.ProjectID
-	if projectID == "" {
-		// https://cloud.google.com/appengine/docs/standard/go/runtime#environment_variables
-		projectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if projectID == "" && extOpts.Format != FormatECS {
+		projectID = envProjectID()
+	}
+	project := func() string { return projectID }
+	if projectID == "" && extOpts.Format != FormatECS {
+		// Querying the metadata server can take a while outside of
+		// Google Cloud, so only do it once a record needs it.
+		project = sync.OnceValue(metadataProjectID)
 	}
-	jsonOpts := *basicOpts
-	jsonOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
-		a = replaceAttr(groups, a)
-		if repl != nil {
-			a = repl(groups, a)
+	svc := extOpts.ServiceContext
+	var envSvc ServiceContext
+	if svc.Service == "" {
+		if extOpts.EnvironmentFields {
+			svc = detectServiceContext()
+		} else {
+			envSvc = detectServiceContext()
 		}
-		return a
 	}
-	return &Handler{
-		base:      slog.NewJSONHandler(w, &jsonOpts),
-		projectID: projectID,
+	level := basicOpts.Level
+	levelVar := extOpts.LevelVar
+	if levelVar == nil {
+		switch l := basicOpts.Level.(type) {
+		case nil:
+			levelVar = new(slog.LevelVar)
+			if l, err := ParseLevel(os.Getenv("LOG_LEVEL")); err == nil {
+				levelVar.Set(l)
+			}
+		case *slog.LevelVar:
+			levelVar = l
+		}
+	}
+	if levelVar != nil {
+		level = levelVar
+	}
+	enc := &encoder{
+		level:         level,
+		addSource:     basicOpts.AddSource,
+		replaceAttr:   basicOpts.ReplaceAttr,
+		replaceValue:  extOpts.ReplaceValue,
+		maxEntryBytes: extOpts.MaxEntryBytes,
+		redactKeys:    slices.Clone(extOpts.RedactKeys),
+		masks:         slices.Clone(extOpts.Masks),
+		severityFunc:  extOpts.SeverityFunc,
+		trimSource:    extOpts.TrimSourcePrefix,
+		format:        extOpts.Format,
+		omitTime:      extOpts.OmitTime,
+		timeFormat:    extOpts.TimeFormat,
+	}
+	var n *notifier
+	if extOpts.Notify != nil {
+		n = &notifier{f: extOpts.Notify, interval: extOpts.NotifyInterval}
+		if n.interval <= 0 {
+			n.interval = time.Minute
+		}
+	}
+	var b *budget
+	if o := extOpts.LogBudget; o != nil {
+		b = &budget{LogBudget: *o, start: time.Now(), suppressed: make(map[string]int)}
+		if b.Window <= 0 {
+			b.Window = time.Minute
+		}
 	}
+	// Explicit labels override labels detected from the environment.
+	labelMap := make(map[string]string)
+	if extOpts.KubernetesLabels {
+		maps.Copy(labelMap, detectLabels(kubernetesEnvLabels))
+	}
+	envLabelMap := detectLabels(envLabels)
+	if extOpts.EnvironmentFields {
+		maps.Copy(labelMap, envLabelMap)
+		envLabelMap = nil
+	}
+	maps.Copy(labelMap, extOpts.Labels)
+	sorted := func(m map[string]string) []slog.Attr {
+		var labels []slog.Attr
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			labels = append(labels, slog.String(k, m[k]))
+		}
+		return labels
+	}
+	// Outputs that write to the same writer share a lockedWriter, so
+	// that their entries can’t interleave.  Only pointers are guaranteed
+	// to be usable as map keys.
+	lws := make(map[io.Writer]*lockedWriter)
+	lock := func(w io.Writer) *lockedWriter {
+		if w == nil || reflect.TypeOf(w).Kind() != reflect.Pointer {
+			return &lockedWriter{w: w}
+		}
+		lw, ok := lws[w]
+		if !ok {
+			lw = &lockedWriter{w: w}
+			lws[w] = lw
+		}
+		return lw
+	}
+	base := output{enc: enc, metrics: extOpts.Metrics, onWriteError: extOpts.OnWriteError}
+	if extOpts.FallbackWriter != nil {
+		base.fallback = lock(extOpts.FallbackWriter)
+	}
+	routes := make([]route, len(extOpts.Routes))
+	for i, r := range extOpts.Routes {
+		routes[i] = route{r.Match, base.withWriter(lock(r.Writer))}
+	}
+	h := &Handler{
+		out:            base.withWriter(lock(w)),
+		routes:         routes,
+		projectID:      project,
+		levelVar:       levelVar,
+		addTraceURL:    extOpts.AddTraceURL,
+		schemaVersion:  extOpts.SchemaVersion,
+		labels:         sorted(labelMap),
+		envLabels:      sorted(envLabelMap),
+		serviceContext: svc.attrs(),
+		envService:     envSvc.attrs(),
+		sourceMinLevel: extOpts.SourceMinLevel,
+		traceExtractor: extOpts.TraceExtractor,
+		errorReporting: extOpts.ErrorReporting,
+		stackMinLevel:  extOpts.AddStackTrace,
+		notifier:       n,
+		budget:         b,
+		prefix:         new(prefix),
+	}
+	if extOpts.AddSequence {
+		h.sequence = new(atomic.Uint64)
+	}
+	return h
 }
 
 // Handler is an [slog.Handler] that sends structured log messages in JSON
 // format.  Use [NewHandler] to create Handler objects; the zero Handler isn’t
 // valid.  Handler objects can’t be copied once created.
+//
+// A Handler writes each entry with a single call to Write.  The handler and
+// all handlers derived from it serialize their writes to the same writer, so
+// entries never interleave even if the writer isn’t safe for concurrent use.
+// Handlers created by separate calls to [NewHandler] don’t synchronize with
+// each other; to share such a writer between them, derive them from a common
+// handler instead.
 type Handler struct {
-	// We use an slog.JSONHandler because that does most of what we want.
-	// We just need to munge the attributes a bit (in Handler.Handle and
-	// replaceAttr).
-	base *slog.JSONHandler
+	// Main output, and additional outputs for Options.Routes.
+	out    output
+	routes []route
 
-	// Empty only if we don’t know the project ID.
-	projectID string
+	// Returns the project ID, or an empty string if we don’t know it.
+	// It might query the metadata server the first time, so only call it
+	// if needed.
+	projectID func() string
 
-	// Attributes added by WithAttrs.
-	attrs []slog.Attr
+	// Whether to add TraceURLKey to error records.
+	addTraceURL bool
+
+	// Value for SchemaVersionKey; empty if none.
+	schemaVersion string
+
+	// Counter for SequenceKey, shared with derived handlers; nil if
+	// Options.AddSequence isn’t set.
+	sequence *atomic.Uint64
+
+	// Labels from Options.Labels and from label attributes added by
+	// WithAttrs.
+	labels []slog.Attr
+
+	// Minimum level for source locations; nil if unrestricted.
+	sourceMinLevel slog.Leveler
+
+	// Additional source of trace information; nil if none.
+	traceExtractor func(context.Context) (Trace, bool)
+
+	// Variable for the minimum level; nil if the level is fixed.
+	levelVar *slog.LevelVar
+
+	// Members of the serviceContext group; nil if none.
+	serviceContext []slog.Attr
+
+	// Labels and members of the serviceContext group detected from the
+	// environment that are only added to records at LevelError or above;
+	// nil if none.  See Options.EnvironmentFields.
+	envLabels, envService []slog.Attr
+
+	// Whether to format error records as error events.
+	errorReporting bool
+
+	// Minimum level for adding stack traces; nil if never.
+	stackMinLevel slog.Leveler
+
+	// Notifier for ALERT and EMERGENCY records; nil if none.
+	notifier *notifier
 
-	// Names of groups added by Handler.WithGroup, from innermost to
-	// outermost.
-	groups []string
+	// Log budget; nil if unlimited.
+	budget *budget
+
+	// Attributes and groups added by WithAttrs and WithGroup, from
+	// outermost to innermost.  We only need them for routes.
+	goas []groupOrAttrs
+
+	// Encoded form of goas.
+	prefix *prefix
+
+	// Whether goas contains a group.
+	grouped bool
+}
+
+// groupOrAttrs is either a group name added by Handler.WithGroup or a list of
+// attributes added by Handler.WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
 }
 
 // Options contains additional options for configuring a [Handler].  It can be
@@ -86,6 +286,279 @@ type Options struct {
 	// Alphanumeric Google Cloud project ID of the current project.  If
 	// empty, NewHandler tries to auto-detect the project ID.
 	ProjectID string
+
+	// If not nil, ReplaceValue is called for the value of every
+	// non-group attribute, including attributes nested in groups and the
+	// log message, but excluding time, severity, and source location.
+	// It’s called with the (innermost) key of the attribute and its
+	// value after [slog.HandlerOptions.ReplaceAttr] has been applied, and
+	// returns the value to encode instead.  This makes it possible to
+	// implement masking and formatting policies without having to
+	// traverse groups manually.
+	ReplaceValue func(key string, v slog.Value) slog.Value
+
+	// If set, records at [LevelError] or above that belong to a traced
+	// request get an additional attribute [TraceURLKey] that links to
+	// the trace in the Google Cloud Console (see [TraceURL]).  This
+	// requires a known project ID.
+	AddTraceURL bool
+
+	// If not nil, Notify is called for every record at [LevelAlert] or
+	// above, with the encoded JSON log entry as argument.  This allows
+	// paging on critical conditions even if log-based alerting lags.
+	// Notify is called asynchronously in a new goroutine, and at most
+	// once per NotifyInterval; additional records are only logged.
+	// [Handler.Flush] waits for running calls.
+	Notify func(entry []byte)
+
+	// Minimum interval between two calls to Notify.  If zero, the
+	// handler uses one minute.
+	NotifyInterval time.Duration
+
+	// If not nil, limit the volume of log records.  See [LogBudget].
+	LogBudget *LogBudget
+
+	// Routes for records that should go to a different writer than the
+	// one passed to NewHandler.  The handler writes each record to the
+	// writer of the first matching route, or to the main writer if no
+	// route matches.
+	Routes []Route
+
+	// Layout for the time of records in the human-readable format of
+	// [NewDevHandler], for example [time.Kitchen] or "15:04:05.000".  If
+	// empty, that format uses RFC 3339 with millisecond precision.  Either
+	// way, it displays times in the local time zone.  The handler returned
+	// by [NewHandler] ignores DevTimeLayout.
+	DevTimeLayout string
+
+	// If not empty, add an attribute [SchemaVersionKey] with this value
+	// to every record.  Downstream consumers can use it to parse records
+	// whose attribute conventions have changed over time.  You can use
+	// the constant [SchemaVersion] or a value derived from it.
+	SchemaVersion string
+
+	// If set, add an attribute [SequenceKey] to every record with a
+	// sequence number that increases by one with each record that the
+	// handler or a handler derived from it writes, starting at one.  With
+	// buffering writers such as [BufferedWriter] or when running many
+	// goroutines, entries can arrive at the backend out of order, and
+	// entries with the same timestamp are otherwise indistinguishable;
+	// the sequence number makes it possible to reconstruct the original
+	// order.  Sequence numbers restart with each process.
+	AddSequence bool
+
+	// If not nil and [slog.HandlerOptions.AddSource] is set, only add
+	// source locations to records at this level or above, for example
+	// [LevelWarn].  Source locations on frequent low-severity records
+	// bloat the log entries.  The handler doesn’t look up source
+	// locations for records below this level, so they don’t cost
+	// anything beyond the program counter that [slog.Logger] captures
+	// anyway.
+	SourceMinLevel slog.Leveler
+
+	// If not nil, the handler calls TraceExtractor to determine the trace
+	// of a record from the context passed to the logging function.  If
+	// TraceExtractor returns false, the handler falls back to the trace
+	// set by [Middleware] or [ContextWithTrace].  This makes it possible
+	// to use tracing libraries that store the active span in the context;
+	// see package [github.com/phst/aelog/aelogotel] for an example.
+	TraceExtractor func(ctx context.Context) (Trace, bool)
+
+	// Labels to add to every log entry, for example to tag entries with
+	// the service or environment.  See [LabelsKey].  On App Engine,
+	// NewHandler also adds the labels “instance_id”, “deployment_id”, and
+	// “runtime” from the environment variables GAE_INSTANCE,
+	// GAE_DEPLOYMENT_ID, and GAE_RUNTIME, so that entries can be filtered
+	// by instance.  On Cloud Run, it adds the labels “service_name”,
+	// “revision_name”, and “configuration_name” from the environment
+	// variables K_SERVICE, K_REVISION, and K_CONFIGURATION, so that
+	// entries from different revisions of a traffic split can be told
+	// apart.  It adds these labels only to error entries unless
+	// EnvironmentFields is set.  Labels overrides them.  See also
+	// KubernetesLabels.
+	Labels map[string]string
+
+	// If set, NewHandler adds the labels “pod_name”, “namespace_name”,
+	// “node_name”, and “container_name” from the environment variables
+	// POD_NAME, POD_NAMESPACE, NODE_NAME, and CONTAINER_NAME if they are
+	// set.  Kubernetes doesn’t set these variables by itself; expose them
+	// in the pod specification using the [Downward API].  This is useful
+	// for GKE workloads whose standard output is collected by the logging
+	// agent.  [Options.Labels] overrides these labels.
+	//
+	// [Downward API]: https://kubernetes.io/docs/concepts/workloads/pods/downward-api/
+	KubernetesLabels bool
+
+	// If set, format records at [LevelError] or above as error events
+	// that [Error Reporting] picks up: add an “@type” field with the value
+	// [ErrorEventType] and a “context” group with the HTTP request and
+	// the source location of the logging call.  Error Reporting then
+	// reports such records even if they don’t contain a stack trace.
+	//
+	// [Error Reporting]: https://cloud.google.com/error-reporting/docs/formatting-error-messages
+	ErrorReporting bool
+
+	// If not nil, add a stack trace of the logging call to records at
+	// this level or above, using the key [StackTraceKey] and the format
+	// of the Go runtime that Error Reporting understands.  Records that
+	// already have a top-level attribute with that key keep it.  Capturing
+	// stack traces is expensive, so this is typically set to
+	// [LevelError].
+	AddStackTrace slog.Leveler
+
+	// Service that writes the log entries.  The handler adds it to each
+	// entry as a group with the key [ServiceContextKey].  If the service
+	// is empty, NewHandler uses the environment variables GAE_SERVICE and
+	// GAE_VERSION on App Engine, or K_SERVICE and K_REVISION on Cloud Run
+	// and Cloud Run functions, but adds the detected service only to
+	// error entries unless EnvironmentFields is set.  A top-level group
+	// with the key [ServiceContextKey] in a record or in
+	// [slog.Logger.With] replaces the service context.
+	ServiceContext ServiceContext
+
+	// If set, add the service context and labels that NewHandler detects
+	// from the environment to every entry.  Otherwise, add them only to
+	// entries at [LevelError] or above, where Error Reporting uses the
+	// service context to group errors, to avoid the extra bytes on every
+	// entry.  See ServiceContext and Labels.
+	EnvironmentFields bool
+
+	// Variable for the minimum level of records to log.  If set, it
+	// overrides [slog.HandlerOptions.Level].  Changing the variable
+	// changes the minimum level of the handler and all handlers derived
+	// from it.  See [NewHandler] for the default.
+	LevelVar *slog.LevelVar
+
+	// If positive, the maximum size of an encoded log entry in bytes.
+	// Cloud Logging rejects entries larger than 256 KiB, so
+	// 256 × 1024 minus some headroom for metadata is a good value.  The
+	// handler truncates the message and long string values of entries
+	// that would exceed the size and adds an attribute [TruncatedKey].
+	// If that isn’t enough, it drops all attributes except for the
+	// built-in ones.
+	MaxEntryBytes int
+
+	// Keys of attributes whose values the handler replaces with the
+	// string “[REDACTED]”, for example “password” or “authorization”.
+	// An entry without dots matches attributes with that key in any
+	// group; an entry with dots such as “request.headers.cookie” also
+	// matches the attribute “cookie” in the group “headers” nested in
+	// the group “request”.  The comparison is case-insensitive.  If
+	// the value of a matching attribute is a group, the handler redacts
+	// the entire group.  Redaction happens after
+	// [slog.HandlerOptions.ReplaceAttr] and ReplaceValue, so they can’t
+	// undo it, and doesn’t apply to the message and the built-in fields.
+	RedactKeys []string
+
+	// Masks for sensitive data such as email addresses in the message,
+	// string values, and error messages; see [Mask].  The handler applies
+	// the masks in order.  It doesn’t scan attribute keys and values that
+	// it encodes as JSON, such as structs and slices.  Scanning every
+	// string is expensive, so keep the list short.
+	Masks []Mask
+
+	// If not nil, SeverityFunc returns the [LogSeverity] for a level.
+	// By default, the handler uses the severity of the lowest level
+	// constant at or above the level, for example “NOTICE” for
+	// [LevelInfo]+1, and “DEBUG” for all levels below [LevelDebug].  This
+	// is useful for applications with their own level conventions, for
+	// example to map a trace level below [LevelDebug] to “DEBUG”, or
+	// certain levels to “DEFAULT”.  SeverityFunc should return one of
+	// the severity names that Cloud Logging understands.
+	//
+	// [LogSeverity]: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogSeverity
+	SeverityFunc func(slog.Level) string
+
+	// Prefix to remove from the file names of source locations, for
+	// example the directory of the main module at build time as returned
+	// by [ModuleRoot].  Full file names are noisy and reveal the layout
+	// of the build machine.  Stack traces are unaffected.
+	TrimSourcePrefix string
+
+	// If not nil, the handler counts the entries it writes and the
+	// records it drops in Metrics.
+	Metrics *Metrics
+
+	// If not nil, the handler writes entries that it fails to write to
+	// the primary writer or the writer of a route to FallbackWriter
+	// instead, for example [os.Stderr], so that they don’t get lost.
+	// Handle then only returns an error if writing to FallbackWriter
+	// fails as well.
+	FallbackWriter io.Writer
+
+	// If not nil, the handler calls OnWriteError with the error whenever
+	// writing an entry to the primary writer or the writer of a route
+	// fails, even if FallbackWriter succeeds.  [slog.Logger] ignores
+	// errors returned by Handle, so this is the only way to find out
+	// about them.  OnWriteError must be safe for concurrent use, and
+	// must not log to the same handler.
+	OnWriteError func(error)
+
+	// Output format; see [Format].  The default is the Cloud Logging
+	// format.  With [FormatECS], NewHandler doesn’t try to detect the
+	// project ID, since it isn’t needed.
+	Format Format
+
+	// If set, the handler doesn’t write the time of records.  Cloud
+	// Logging then uses the time at which it received the entry, which is
+	// usually close enough and saves about 40 bytes per entry.  This is
+	// only worthwhile for services that log extremely many entries.
+	OmitTime bool
+
+	// Serialization of the time of records and of time-valued attributes;
+	// see [TimeFormat].  The default is [TimeFormatRFC3339Nano].
+	TimeFormat TimeFormat
+}
+
+// LabelsKey is the [special key] for the user-defined labels of a log entry.
+// Labels are string-valued and indexed, so they are cheaper to filter on than
+// fields of the JSON payload.
+//
+// [special key]: https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+const LabelsKey = "logging.googleapis.com/labels"
+
+// SchemaVersionKey is the key of the attribute added by
+// [Options.SchemaVersion].
+const SchemaVersionKey = "logSchemaVersion"
+
+// SequenceKey is the key of the attribute added by [Options.AddSequence].
+const SequenceKey = "sequence"
+
+// SchemaVersion is the version of the structure of the records that [Handler]
+// writes, that is, the special fields and attributes defined by this package.
+// It changes whenever that structure changes in an incompatible way.
+const SchemaVersion = "aelog/1"
+
+// Route sends records matching a predicate to a separate writer, for example
+// to write audit records to a separate file.  See [Options.Routes].
+type Route struct {
+	// Match reports whether a record should go to Writer.  The handler
+	// calls Match after adding all attributes to the record, including
+	// attributes added by [slog.Logger.With] and attributes derived from
+	// the context such as the trace, so Match can use them to make its
+	// decision.  Match also receives the context passed to the logging
+	// function, for example to call [TenantFromContext].  It must not be
+	// nil.
+	Match func(ctx context.Context, r slog.Record) bool
+
+	// Writer for matching records.  It must not be nil.
+	Writer io.Writer
+}
+
+// LevelRoute returns a route that sends records at the given level or above
+// to w.  This is typically used to split the output by severity:
+//
+//	h := aelog.NewHandler(os.Stdout, nil, &aelog.Options{
+//		Routes: []aelog.Route{aelog.LevelRoute(aelog.LevelWarn, os.Stderr)},
+//	})
+//
+// Some platforms, such as Cloud Run and GKE, infer the severity of lines that
+// aren’t valid JSON from the stream they were written to.
+func LevelRoute(min slog.Leveler, w io.Writer) Route {
+	return Route{
+		Match:  func(_ context.Context, r slog.Record) bool { return r.Level >= min.Level() },
+		Writer: w,
+	}
 }
 
 // Constants for [special keys] in the output record.
@@ -98,13 +571,82 @@ const (
 	SourceLocationKey = "logging.googleapis.com/sourceLocation"
 )
 
+// LevelVar returns the variable for the minimum level of the handler.  Setting
+// the variable changes the level of the handler and all handlers derived from
+// it without recreating them.  LevelVar returns nil if
+// [slog.HandlerOptions.Level] specified a leveler other than a
+// [slog.LevelVar].
+func (h *Handler) LevelVar() *slog.LevelVar {
+	return h.levelVar
+}
+
 // Enabled implements [slog.Handler.Enabled].
 func (h *Handler) Enabled(ctx context.Context, l slog.Level) bool {
-	return h.base.Enabled(ctx, l)
+	return h.out.enc.enabled(l)
 }
 
 // Handle implements [slog.Handler.Handle].
 func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if h.budget != nil {
+		keep, summary := h.budget.check(r.Level, r.Message, h.written())
+		if summary != nil {
+			if err := h.out.handle(entry{Record: *summary}); err != nil {
+				return err
+			}
+		}
+		if !keep {
+			h.out.metrics.drop(1)
+			return nil
+		}
+	}
+
+	markLogged(ctx, r.Level)
+	if rtrace.IsEnabled() {
+		// See MiddlewareOptions.TraceTasks.
+		rtrace.Log(ctx, h.out.enc.severity(r.Level), r.Message)
+	}
+
+	// Collect the record attributes, moving labels and operation markers
+	// out of the way.
+	labels := h.labels
+	svc := h.serviceContext
+	// Context attributes are always at the top level.  Labels from the
+	// record override labels from the context.
+	var ctxAttrs []slog.Attr
+	for _, a := range attrsFromContext(ctx) {
+		if l, ok := labelAttrs(a, true); ok {
+			labels = mergeLabels(labels, l)
+		} else if c, ok := serviceContextAttrs(a, true); ok {
+			svc = c
+		} else {
+			ctxAttrs = append(ctxAttrs, a)
+		}
+	}
+	var opFirst, opLast, hasStack bool
+	p := attrsPool.Get().(*[]slog.Attr)
+	defer putAttrs(p)
+	attrs := (*p)[:0]
+	r.Attrs(func(a slog.Attr) bool {
+		if l, ok := labelAttrs(a, !h.grouped); ok {
+			labels = mergeLabels(labels, l)
+		} else if c, ok := serviceContextAttrs(a, !h.grouped); ok {
+			svc = c
+		} else if marker, first := isOperationMarker(a); marker {
+			opFirst = opFirst || first
+			opLast = opLast || !first
+		} else if a.Key != MessageKey {
+			hasStack = hasStack || !h.grouped && a.Key == StackTraceKey
+			attrs = append(attrs, a)
+		}
+		return true
+	})
+	*p = attrs // keep the capacity if attrs has grown
+	labels, svc = h.envFields(r.Level, labels, svc)
+	pc := r.PC
+	if h.sourceMinLevel != nil && r.Level < h.sourceMinLevel.Level() {
+		pc = 0
+	}
+
 	// See
 	// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
 	// for a description of the fields that we set here.
@@ -113,30 +655,140 @@ func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
 	// (time, level, message, program counter) as much as possible.  The
 	// slog.Record structure contains an optimization that stores a few
 	// attributes inline.  By not using attributes for the standard fields
-	// we can support that optimization a bit.  The replaceAttr function
-	// will convert the attributes to the corresponding log record fields.
-	s := slog.NewRecord(r.Time.UTC(), r.Level, r.Message, r.PC)
-	s.AddAttrs(httpAttrs(ctx, h.projectID)...)
-	if n := len(h.attrs) + r.NumAttrs(); n > 0 {
-		attrs := append(make([]slog.Attr, 0, n), h.attrs...)
-		r.Attrs(func(a slog.Attr) bool {
-			if a.Key != MessageKey {
-				attrs = append(attrs, a)
+	// we can support that optimization a bit.  The encoder writes the
+	// standard fields as the corresponding log record fields.
+	s := slog.NewRecord(r.Time.UTC(), r.Level, r.Message, pc)
+	if h.schemaVersion != "" {
+		s.AddAttrs(slog.String(SchemaVersionKey, h.schemaVersion))
+	}
+	if h.sequence != nil {
+		s.AddAttrs(slog.Uint64(SequenceKey, h.sequence.Add(1)))
+	}
+	if len(labels) > 0 {
+		s.AddAttrs(slog.Attr{Key: LabelsKey, Value: slog.GroupValue(labels...)})
+	}
+	if len(svc) > 0 {
+		s.AddAttrs(slog.Attr{Key: ServiceContextKey, Value: slog.GroupValue(svc...)})
+	}
+	s.AddAttrs(operationAttrs(ctx, opFirst, opLast)...)
+	if h.errorReporting && r.Level >= LevelError {
+		// Use the original program counter, so that error events have
+		// a location even if SourceMinLevel suppresses the source
+		// location.
+		s.AddAttrs(errorReportingAttrs(ctx, r.PC, h.out.enc.trimSource)...)
+	}
+	if h.stackMinLevel != nil && r.Level >= h.stackMinLevel.Level() && r.PC != 0 && !hasStack {
+		s.AddAttrs(slog.String(StackTraceKey, callerStack(r.PC)))
+	}
+	s.AddAttrs(ctxAttrs...)
+	trace := h.trace(ctx)
+	var projectID string
+	if trace.ID != "" {
+		projectID = h.projectID()
+	}
+	if projectID == "" && h.out.enc.format == FormatECS {
+		// ECS doesn’t need the project ID, see ecsAttr.
+		projectID = "-"
+	}
+	// Use the free part of the pooled slice for the HTTP attributes.
+	s.AddAttrs(appendHTTPAttrs(attrs[len(attrs):], ctx, projectID, trace)...)
+	if h.addTraceURL && r.Level >= LevelError && projectID != "" && projectID != "-" && trace.ID != "" {
+		s.AddAttrs(slog.String(TraceURLKey, TraceURL(projectID, trace.ID)))
+	}
+	e := entry{Record: s, prefix: h.prefix, attrs: attrs}
+	out := h.out
+	if len(h.routes) > 0 {
+		// Routes need to see the complete record.
+		full := h.nest(s, attrs)
+		for _, rt := range h.routes {
+			if rt.match(ctx, full) {
+				out = rt.out
+				break
 			}
-			return true
-		})
-		for _, g := range h.groups {
-			attrs = []slog.Attr{{Key: g, Value: slog.GroupValue(attrs...)}}
 		}
-		s.AddAttrs(attrs...)
 	}
-	return h.base.Handle(ctx, s)
+	if i := infoFromContext(ctx); i != nil && i.tail != nil {
+		if i.tail.add(ctx, out, e) {
+			return nil
+		}
+	}
+	notify := h.notifier != nil && r.Level >= LevelAlert
+	if b := batchFromContext(ctx); b != nil && !notify {
+		if b.add(h, out, e) {
+			return nil
+		}
+	}
+	if notify {
+		return h.handleNotify(out, e)
+	}
+	return out.handle(e)
+}
+
+// nest returns a copy of r with attrs and the attributes and groups added by
+// WithAttrs and WithGroup.
+func (h *Handler) nest(r slog.Record, attrs []slog.Attr) slog.Record {
+	r = r.Clone()
+	// Nest the attributes from the innermost group outwards.  Groups
+	// without any attributes are dropped entirely.
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group == "" {
+			attrs = append(slices.Clip(goa.attrs), attrs...)
+		} else if len(attrs) > 0 {
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+	r.AddAttrs(attrs...)
+	return r
+}
+
+// envFields adds the labels and service context detected from the
+// environment to the given ones if the level requires them.  Labels and
+// service contexts from other sources take precedence.  See
+// Options.EnvironmentFields.
+func (h *Handler) envFields(level slog.Level, labels, svc []slog.Attr) ([]slog.Attr, []slog.Attr) {
+	if level < LevelError {
+		return labels, svc
+	}
+	if len(h.envLabels) > 0 {
+		labels = mergeLabels(h.envLabels, labels)
+	}
+	if len(svc) == 0 {
+		svc = h.envService
+	}
+	return labels, svc
 }
 
 // WithAttrs implements [slog.Handler.WithAttrs].
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	r := h.clone()
-	r.attrs = append(r.attrs, attrs...)
+	if len(attrs) == 0 {
+		return h
+	}
+	var labels, svc []slog.Attr
+	rest := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if l, ok := labelAttrs(a, !h.grouped); ok {
+			labels = append(labels, l...)
+		} else if c, ok := serviceContextAttrs(a, !h.grouped); ok {
+			svc = c
+		} else {
+			rest = append(rest, a)
+		}
+	}
+	r := h
+	if len(rest) > 0 {
+		r = h.with(groupOrAttrs{attrs: rest})
+		r.prefix = h.out.enc.withAttrs(h.prefix, rest)
+	} else {
+		c := *h
+		r = &c
+	}
+	if len(labels) > 0 {
+		r.labels = mergeLabels(h.labels, labels)
+	}
+	if svc != nil {
+		r.serviceContext = svc
+	}
 	return r
 }
 
@@ -145,18 +797,227 @@ func (h *Handler) WithGroup(name string) slog.Handler {
 	if name == "" {
 		return h
 	}
-	r := h.clone()
-	r.groups = append([]string{name}, r.groups...)
+	r := h.with(groupOrAttrs{group: name})
+	r.prefix = h.prefix.withGroup(name)
+	r.grouped = true
 	return r
 }
 
-func (h *Handler) clone() *Handler {
+// output is a destination for log records.
+type output struct {
+	// Encoder for the records, shared by all outputs of a handler.
+	enc *encoder
+
+	// Writer for the encoded records.
+	w *lockedWriter
+
+	// Options shared by all outputs of a handler.  See
+	// Options.FallbackWriter, Options.OnWriteError, and Options.Metrics.
+	fallback     *lockedWriter
+	onWriteError func(error)
+	metrics      *Metrics
+}
+
+// withWriter returns a copy of o that writes to w.
+func (o output) withWriter(w *lockedWriter) output {
+	o.w = w
+	return o
+}
+
+// handle encodes r and writes it with a single call to Write.
+func (o output) handle(e entry) error {
+	return o.write(e, nil)
+}
+
+// write implements handle.  If notify isn’t nil, write also passes the
+// encoded entry to it, whether or not writing succeeds.  The entry is only
+// valid during the call to notify.
+func (o output) write(e entry, notify func([]byte)) error {
+	p := bufferPool.Get().(*[]byte)
+	b := o.enc.appendEntry((*p)[:0], e)
+	if notify != nil {
+		notify(b)
+	}
+	_, err := o.w.Write(b)
+	if err != nil {
+		if o.onWriteError != nil {
+			o.onWriteError(err)
+		}
+		if o.fallback != nil {
+			if _, ferr := o.fallback.Write(b); ferr == nil {
+				err = nil
+			} else {
+				err = errors.Join(err, ferr)
+			}
+		}
+	}
+	if o.metrics != nil {
+		if err == nil {
+			o.metrics.written(o.enc.severity(e.Level), len(b))
+		} else {
+			o.metrics.drop(1)
+		}
+	}
+	if cap(b) <= maxBufferSize {
+		*p = b
+		bufferPool.Put(p)
+	}
+	return err
+}
+
+// Flush flushes the writers of the handler, including the writers of
+// [Options.Routes] and [Options.FallbackWriter], that buffer their output,
+// that is, writers that have a method
+//
+//	Flush() error
+//
+// such as [BufferedWriter].  It returns the errors of all failed writers
+// joined using [errors.Join].  Call Flush before the process exits so that no
+// records get lost.  If [Options.Notify] is set, Flush also waits up to five
+// seconds for notifications that are still running, so that the notification
+// for a record that ends the process, for example one logged by [Fatal], gets
+// sent.
+func (h *Handler) Flush() error {
+	var errs []error
+	if h.notifier != nil && !h.notifier.wait(notifyWaitTimeout) {
+		errs = append(errs, errors.New("aelog: timed out waiting for notifications"))
+	}
+	for _, w := range h.writers() {
+		if f, ok := w.(flusher); ok {
+			errs = append(errs, f.Flush())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes the handler (see [Handler.Flush]) and then closes the writers
+// of the handler that implement [io.Closer], except for writers of type
+// [*os.File], which are typically standard streams owned by the caller.  It
+// returns the errors of all failed writers joined using [errors.Join].  The
+// handler must not be used after Close.
+func (h *Handler) Close() error {
+	errs := []error{h.Flush()}
+	for _, w := range h.writers() {
+		if _, ok := w.(*os.File); ok {
+			continue
+		}
+		if c, ok := w.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writers returns the distinct underlying writers of all outputs.
+func (h *Handler) writers() []io.Writer {
+	var ws []io.Writer
+	for _, w := range h.lockedWriters() {
+		ws = append(ws, w.w)
+	}
+	return ws
+}
+
+// route is the internal form of a Route.
+type route struct {
+	match func(context.Context, slog.Record) bool
+	out   output
+}
+
+// written returns the total number of bytes written to all outputs so far.
+func (h *Handler) written() int64 {
+	var n int64
+	for _, w := range h.lockedWriters() {
+		n += w.written()
+	}
+	return n
+}
+
+// lockedWriters returns the distinct writers of all outputs, including the
+// fallback writer.
+func (h *Handler) lockedWriters() []*lockedWriter {
+	ws := []*lockedWriter{h.out.w}
+	for _, rt := range h.routes {
+		if !slices.Contains(ws, rt.out.w) {
+			ws = append(ws, rt.out.w)
+		}
+	}
+	if f := h.out.fallback; f != nil && !slices.Contains(ws, f) {
+		ws = append(ws, f)
+	}
+	return ws
+}
+
+// lockedWriter serializes writes to an underlying writer.  The encoder itself
+// doesn’t synchronize anything.  Since output.handle writes each entry with a
+// single call to Write, entries written by different goroutines never
+// interleave, even if the underlying writer isn’t safe for concurrent use.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	n  int64 // number of bytes written, guarded by mu
+}
+
+func (w *lockedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	total := 0
+	// Writers must return an error if they write less than len(b), but
+	// be defensive so that we never leave a partial line behind.
+	for len(b) > 0 {
+		n, err := w.w.Write(b)
+		total += n
+		w.n += int64(n)
+		if err != nil {
+			return total, err
+		}
+		if n == 0 {
+			return total, io.ErrShortWrite
+		}
+		b = b[n:]
+	}
+	return total, nil
+}
+
+// written returns the total number of bytes written so far.
+func (w *lockedWriter) written() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.n
+}
+
+// trace returns the trace for a record.  An empty trace ID means there’s no
+// trace.
+func (h *Handler) trace(ctx context.Context) Trace {
+	if h.traceExtractor != nil {
+		if t, ok := h.traceExtractor(ctx); ok && t.ID != "" {
+			return t
+		}
+	}
+	t, _ := TraceFromContext(ctx)
+	return t
+}
+
+func (h *Handler) with(goa groupOrAttrs) *Handler {
 	r := *h
-	r.attrs = slices.Clone(h.attrs)
-	r.groups = slices.Clone(h.groups)
+	r.goas = append(slices.Clip(h.goas), goa)
 	return &r
 }
 
+// isBuiltin returns whether the attribute key refers to one of the built-in
+// fields that aren’t user data.  It must be called with the key as returned
+// by replaceAttr.
+func isBuiltin(groups []string, key string) bool {
+	if len(groups) > 0 {
+		return false
+	}
+	switch key {
+	case TimeKey, SeverityKey, SourceLocationKey:
+		return true
+	default:
+		return false
+	}
+}
+
 func replaceAttr(groups []string, a slog.Attr) slog.Attr {
 	if len(groups) > 0 {
 		// If we’re inside a group, don’t do anything.  Only top-level
@@ -1,4 +1,4 @@
-// Copyright 2023, 2024 Google LLC
+// Copyright 2023, 2024, 2026 Google LLC
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -19,13 +19,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"runtime"
 	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/slogtest"
 	"time"
@@ -122,6 +129,76 @@ func TestHandler_attrs(t *testing.T) {
 	}
 }
 
+func TestHandler_encoding(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.String("html", "<a href=\"x\">&</a>"),
+		slog.String("control", "a\tb\nc\rd\x00e\x1f\\"),
+		slog.String("unicode", "ä\u2028\u2029"),
+		slog.Int("int", -123),
+		slog.Uint64("uint", 123),
+		slog.Float64("float", 1.5),
+		slog.Float64("small", 1e-7),
+		slog.Float64("large", 1e21),
+		slog.Float64("nan", math.NaN()),
+		slog.Bool("bool", true),
+		slog.Duration("duration", 1500*time.Millisecond),
+		slog.Time("time", time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)),
+		slog.Time("invalid", time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)),
+		slog.Any("error", errors.New("<error>")),
+		slog.Any("marshaler", json.RawMessage(`{"a":[1,2]}`)),
+		slog.Any("map", map[string]int{"<": 1}),
+		slog.Any("nil", nil),
+		slog.Any("", nil),
+		slog.Group("empty"),
+		slog.Group("", slog.Int("inline", 1)),
+		slog.Group("nested", slog.Group("inner", slog.Int("a", 1)), slog.Int("b", 2)),
+		slog.Any("valuer", tokenValuer("secret")),
+	}
+	for _, tc := range []struct {
+		name string
+		opts *slog.HandlerOptions
+	}{
+		{"default", nil},
+		{"ReplaceAttr", &slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "a" {
+				// Make the group “inner” empty.
+				return slog.Attr{}
+			}
+			return a
+		}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Compare with the output of slog.JSONHandler, ignoring the
+			// built-in fields.
+			var got, want bytes.Buffer
+			h := aelog.NewHandler(&got, tc.opts, nil)
+			j := slog.NewJSONHandler(&want, tc.opts)
+			for _, h := range []slog.Handler{h.WithGroup("g"), j.WithGroup("g")} {
+				r := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+				r.AddAttrs(attrs...)
+				if err := h.Handle(context.Background(), r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			gotAttrs, ok := strings.CutPrefix(got.String(), `{"severity":"INFO","message":"msg",`)
+			if !ok {
+				t.Fatalf("unexpected output %s", got.String())
+			}
+			wantAttrs, ok := strings.CutPrefix(want.String(), `{"level":"INFO","msg":"msg",`)
+			if !ok {
+				t.Fatalf("unexpected output %s", want.String())
+			}
+			if diff := cmp.Diff(gotAttrs, wantAttrs); diff != "" {
+				t.Error("-got +want", diff)
+			}
+		})
+	}
+}
+
+type tokenValuer string
+
+func (tokenValuer) LogValue() slog.Value { return slog.StringValue("REDACTED") }
+
 func TestHandler_time(t *testing.T) {
 	buf := new(bytes.Buffer)
 	log := slog.New(aelog.NewHandler(buf, nil, nil))
@@ -205,6 +282,56 @@ func TestHandler_WithAttrs(t *testing.T) {
 	}
 }
 
+func TestHandler_WithAttrs_nested(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		with func(slog.Handler) slog.Handler
+	}{
+		{"attrs", func(h slog.Handler) slog.Handler {
+			return h.WithAttrs([]slog.Attr{slog.Int("a", 1)})
+		}},
+		{"group", func(h slog.Handler) slog.Handler {
+			return h.WithGroup("g")
+		}},
+		{"group attrs", func(h slog.Handler) slog.Handler {
+			return h.WithGroup("g").WithAttrs([]slog.Attr{slog.Int("a", 1)})
+		}},
+		{"attrs group", func(h slog.Handler) slog.Handler {
+			return h.WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g")
+		}},
+		{"deep", func(h slog.Handler) slog.Handler {
+			return h.WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g").WithGroup("h").
+				WithAttrs([]slog.Attr{slog.Int("b", 2)}).WithGroup("i").
+				WithAttrs([]slog.Attr{slog.Group("empty")}).WithGroup("j")
+		}},
+		{"valuer", func(h slog.Handler) slog.Handler {
+			return h.WithGroup("g").WithAttrs([]slog.Attr{slog.Any("token", tokenValuer("secret"))})
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, attrs := range [][]slog.Attr{nil, {slog.Int("c", 3)}} {
+				// Compare with the output of slog.JSONHandler,
+				// ignoring the built-in fields.
+				var got, want bytes.Buffer
+				h := tc.with(aelog.NewHandler(&got, nil, nil))
+				j := tc.with(slog.NewJSONHandler(&want, nil))
+				for _, h := range []slog.Handler{h, j} {
+					r := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+					r.AddAttrs(attrs...)
+					if err := h.Handle(context.Background(), r); err != nil {
+						t.Fatal(err)
+					}
+				}
+				gotAttrs := strings.TrimPrefix(got.String(), `{"severity":"INFO","message":"msg"`)
+				wantAttrs := strings.TrimPrefix(want.String(), `{"level":"INFO","msg":"msg"`)
+				if diff := cmp.Diff(gotAttrs, wantAttrs); diff != "" {
+					t.Errorf("record attributes %v: -got +want\n%s", attrs, diff)
+				}
+			}
+		})
+	}
+}
+
 func TestHandler_WithGroup(t *testing.T) {
 	buf := new(bytes.Buffer)
 	log := slog.New(aelog.NewHandler(buf, nil, nil))
@@ -224,14 +351,781 @@ func TestHandler_WithGroup(t *testing.T) {
 	}
 }
 
+func TestHandler_ReplaceValue(t *testing.T) {
+	buf := new(bytes.Buffer)
+	mask := func(key string, v slog.Value) slog.Value {
+		if v.Kind() == slog.KindString && strings.Contains(key, "secret") {
+			return slog.StringValue("***")
+		}
+		if v.Kind() == slog.KindString && key == aelog.MessageKey {
+			return slog.StringValue(strings.ToUpper(v.String()))
+		}
+		return v
+	}
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{AddSource: true}, &aelog.Options{ReplaceValue: mask}))
+
+	log.WithGroup("outer").Info(
+		"message",
+		"secret", "top",
+		"attr", 123,
+		slog.Group("inner", "my-secret", "nested", "other", "value"),
+	)
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity": "INFO",
+		"message":  "MESSAGE",
+		"outer": map[string]any{
+			"secret": "***",
+			"attr":   123.0,
+			"inner": map[string]any{
+				"my-secret": "***",
+				"other":     "value",
+			},
+		},
+	}}
+	if diff := cmp.Diff(got, want, ignoreFields(aelog.TimeKey, aelog.SourceLocationKey)); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestHandler_ReplaceAttr_groups(t *testing.T) {
+	buf := new(bytes.Buffer)
+	var paths []string
+	redact := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "password" {
+			paths = append(paths, strings.Join(append(groups, a.Key), "."))
+			a.Value = slog.StringValue("REDACTED")
+		}
+		return a
+	}
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{ReplaceAttr: redact}, nil))
+
+	log.With("password", "a").WithGroup("g").With("user", "b").Info(
+		"message",
+		"password", "c",
+		slog.Group("h", "password", "d"),
+	)
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity": "INFO",
+		"message":  "message",
+		"password": "REDACTED",
+		"g": map[string]any{
+			"user":     "b",
+			"password": "REDACTED",
+			"h":        map[string]any{"password": "REDACTED"},
+		},
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	wantPaths := []string{"password", "g.password", "g.h.password"}
+	if diff := cmp.Diff(paths, wantPaths); diff != "" {
+		t.Error("group paths: -got +want", diff)
+	}
+}
+
+func TestOptions_Routes(t *testing.T) {
+	main := new(bytes.Buffer)
+	audit := new(bytes.Buffer)
+	traced := new(bytes.Buffer)
+	isAudit := func(_ context.Context, r slog.Record) bool {
+		found := false
+		r.Attrs(func(a slog.Attr) bool {
+			found = a.Key == "audit" && a.Value.Bool()
+			return !found
+		})
+		return found
+	}
+	isTraced := func(_ context.Context, r slog.Record) bool {
+		found := false
+		r.Attrs(func(a slog.Attr) bool {
+			found = a.Key == "logging.googleapis.com/trace"
+			return !found
+		})
+		return found
+	}
+	log := slog.New(aelog.NewHandler(main, nil, &aelog.Options{
+		ProjectID: "test",
+		Routes: []aelog.Route{
+			{Match: isAudit, Writer: audit},
+			{Match: isTraced, Writer: traced},
+		},
+	}))
+
+	log.Info("normal")
+	log.With("audit", true).Info("audit")
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		log.InfoContext(r.Context(), "traced")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "abc/123;o=1")
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, tc := range []struct {
+		name string
+		buf  *bytes.Buffer
+		want []map[string]any
+	}{
+		{"main", main, []map[string]any{{"severity": "INFO", "message": "normal"}}},
+		{"audit", audit, []map[string]any{{"severity": "INFO", "message": "audit", "audit": true}}},
+		{"traced", traced, []map[string]any{{"severity": "INFO", "message": "traced"}}},
+	} {
+		got := parseRecords(t, tc.buf)
+		opt := ignoreFields(aelog.TimeKey, "httpRequest", "logging.googleapis.com/trace", "logging.googleapis.com/spanId", "logging.googleapis.com/trace_sampled")
+		if diff := cmp.Diff(got, tc.want, opt); diff != "" {
+			t.Errorf("%s: -got +want %s", tc.name, diff)
+		}
+	}
+}
+
+func TestLevelRoute(t *testing.T) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(stdout, nil, &aelog.Options{
+		Routes: []aelog.Route{aelog.LevelRoute(aelog.LevelWarn, stderr)},
+	}))
+	log.Info("info")
+	log.Warn("warning")
+	log.Error("error")
+
+	for _, tc := range []struct {
+		name string
+		buf  *bytes.Buffer
+		want []map[string]any
+	}{
+		{"stdout", stdout, []map[string]any{{"severity": "INFO", "message": "info"}}},
+		{"stderr", stderr, []map[string]any{
+			{"severity": "WARNING", "message": "warning"},
+			{"severity": "ERROR", "message": "error"},
+		}},
+	} {
+		if diff := cmp.Diff(parseRecords(t, tc.buf), tc.want, ignoreTime); diff != "" {
+			t.Errorf("%s: -got +want %s", tc.name, diff)
+		}
+	}
+}
+
+func TestOptions_SchemaVersion(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{SchemaVersion: aelog.SchemaVersion}))
+	log.WithGroup("g").Info("info", "a", 1)
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity":         "INFO",
+		"message":          "info",
+		"logSchemaVersion": aelog.SchemaVersion,
+		"g":                map[string]any{"a": 1.0},
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestOptions_MaxEntryBytes(t *testing.T) {
+	const maxBytes = 1000
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{MaxEntryBytes: maxBytes}))
+	long := strings.Repeat("x", 2*maxBytes)
+	var many []any
+	for i := range maxBytes {
+		many = append(many, strconv.Itoa(i), i)
+	}
+
+	log.Info("short", "a", 1)
+	log.Info("long", "a", long, "b", 2)
+	log.Info("many", many...)
+	log.Info(long)
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i, line := range lines {
+		if len(line) > maxBytes {
+			t.Errorf("line %d has %d bytes, want at most %d", i, len(line), maxBytes)
+		}
+	}
+	got := parseRecords(t, buf)
+	truncated := func(s string) bool { return strings.HasPrefix(long, s) && len(s) < len(long) }
+	want := []map[string]any{
+		{"severity": "INFO", "message": "short", "a": 1.0},
+		{"severity": "INFO", "message": "long", "a": "", "b": 2.0, "truncated": true},
+		{"severity": "INFO", "message": "many", "truncated": true},
+		{"severity": "INFO", "message": "", "truncated": true},
+	}
+	opt := cmp.FilterValues(func(a, b string) bool {
+		return a == "" && truncated(b) || b == "" && truncated(a)
+	}, cmp.Comparer(func(a, b string) bool { return true }))
+	if diff := cmp.Diff(got, want, ignoreTime, opt); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestOptions_RedactKeys(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{AddSource: true}, &aelog.Options{
+		RedactKeys: []string{"password", "Authorization", "request.headers.cookie", "file"},
+	}))
+	log.With("password", "a").WithGroup("request").Info(
+		"message",
+		slog.Group("headers", "authorization", "Bearer b", "cookie", "c", "accept", "*/*"),
+		"cookie", "d",
+		slog.Group("password", "hash", "e"),
+	)
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity": "INFO",
+		"message":  "message",
+		"password": "[REDACTED]",
+		"request": map[string]any{
+			"headers": map[string]any{
+				"authorization": "[REDACTED]",
+				"cookie":        "[REDACTED]",
+				"accept":        "*/*",
+			},
+			"cookie":   "d",
+			"password": "[REDACTED]",
+		},
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime, ignoreFields("logging.googleapis.com/sourceLocation")); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	if loc, ok := got[0]["logging.googleapis.com/sourceLocation"].(map[string]any); !ok || loc["file"] == "[REDACTED]" {
+		t.Errorf("source location %v redacted", loc)
+	}
+}
+
+func TestOptions_SeverityFunc(t *testing.T) {
+	const levelTrace = aelog.LevelDebug - 4
+	severity := func(l slog.Level) string {
+		switch {
+		case l <= aelog.LevelDebug:
+			return "DEBUG"
+		case l == aelog.LevelInfo+1:
+			return "DEFAULT"
+		default:
+			return strings.ToUpper(l.String())
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		opts *slog.HandlerOptions
+	}{
+		{"default", &slog.HandlerOptions{Level: levelTrace}},
+		{"ReplaceAttr", &slog.HandlerOptions{
+			Level:       levelTrace,
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr { return a },
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			log := slog.New(aelog.NewHandler(buf, tc.opts, &aelog.Options{SeverityFunc: severity}))
+			log.Log(context.Background(), levelTrace, "trace")
+			log.Log(context.Background(), aelog.LevelInfo+1, "default")
+			log.Warn("warning")
+
+			got := parseRecords(t, buf)
+			want := []map[string]any{
+				{"severity": "DEBUG", "message": "trace"},
+				{"severity": "DEFAULT", "message": "default"},
+				{"severity": "WARN", "message": "warning"},
+			}
+			if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+				t.Error("-got +want", diff)
+			}
+		})
+	}
+}
+
+func TestOptions_SourceMinLevel(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{AddSource: true}, &aelog.Options{SourceMinLevel: aelog.LevelWarn}))
+	log.Info("info")
+	log.Warn("warning")
+
+	got := parseRecords(t, buf)
+	if len(got) != 2 {
+		t.Fatalf("got %d records, want two", len(got))
+	}
+	if loc, ok := got[0][aelog.SourceLocationKey]; ok {
+		t.Errorf("INFO record has source location %v", loc)
+	}
+	if _, ok := got[1][aelog.SourceLocationKey].(map[string]any); !ok {
+		t.Error("WARNING record has no source location")
+	}
+}
+
+func TestOptions_FallbackWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	var errs []error
+	h := aelog.NewHandler(failingWriter{}, nil, &aelog.Options{
+		FallbackWriter: buf,
+		OnWriteError:   func(err error) { errs = append(errs, err) },
+	})
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), aelog.LevelInfo, "info", 0)); err != nil {
+		t.Errorf("Handle: %s", err)
+	}
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{"severity": "INFO", "message": "info"}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	if len(errs) != 1 {
+		t.Errorf("got errors %v, want one error", errs)
+	}
+
+	h = aelog.NewHandler(failingWriter{}, nil, &aelog.Options{FallbackWriter: failingWriter{}})
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), aelog.LevelInfo, "info", 0)); err == nil {
+		t.Error("Handle succeeded even though both writers failed")
+	}
+}
+
+func TestNewHandler_projectEnv(t *testing.T) {
+	// Make sure we don’t detect a project using the metadata server.
+	metadata := httptest.NewServer(http.NotFoundHandler())
+	defer metadata.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(metadata.URL, "http://"))
+	aelog.ResetMetadataCache()
+	t.Cleanup(aelog.ResetMetadataCache)
+
+	for _, tc := range []struct {
+		env  map[string]string
+		want any
+	}{
+		{map[string]string{"GCP_PROJECT": "gcp", "GOOGLE_CLOUD_QUOTA_PROJECT": "quota"}, "projects/gcp/traces/abc"},
+		{map[string]string{"GOOGLE_CLOUD_QUOTA_PROJECT": "quota"}, "projects/quota/traces/abc"},
+		{map[string]string{"GOOGLE_CLOUD_PROJECT": "main", "GCLOUD_PROJECT": "gcloud"}, "projects/main/traces/abc"},
+		{nil, nil},
+	} {
+		for _, name := range []string{"GOOGLE_CLOUD_PROJECT", "GCLOUD_PROJECT", "GCP_PROJECT", "GOOGLE_CLOUD_QUOTA_PROJECT"} {
+			t.Setenv(name, tc.env[name])
+		}
+		buf := new(bytes.Buffer)
+		log := slog.New(aelog.NewHandler(buf, nil, nil))
+		log.InfoContext(aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "abc"}), "info")
+		got := parseRecords(t, buf)
+		if len(got) != 1 {
+			t.Fatalf("got %d records, want one", len(got))
+		}
+		if diff := cmp.Diff(got[0]["logging.googleapis.com/trace"], tc.want); diff != "" {
+			t.Errorf("environment %v: -got +want %s", tc.env, diff)
+		}
+	}
+}
+
+func TestNewHandler_metadata(t *testing.T) {
+	var requests atomic.Int32
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/computeMetadata/v1/project/project-id" || r.Header.Get("Metadata-Flavor") != "Google" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Metadata-Flavor", "Google")
+		io.WriteString(w, "meta")
+	}))
+	defer metadata.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(metadata.URL, "http://"))
+	aelog.ResetMetadataCache()
+	t.Cleanup(aelog.ResetMetadataCache)
+	for _, name := range []string{"GOOGLE_CLOUD_PROJECT", "GCLOUD_PROJECT", "GCP_PROJECT", "GOOGLE_CLOUD_QUOTA_PROJECT"} {
+		t.Setenv(name, "")
+	}
+
+	// The handler only queries the metadata server once it needs the
+	// project ID.
+	slog.New(aelog.NewHandler(io.Discard, nil, nil)).Info("no trace")
+	if got := requests.Load(); got != 0 {
+		t.Errorf("metadata server received %d requests before a trace was logged, want none", got)
+	}
+
+	for range 2 {
+		buf := new(bytes.Buffer)
+		log := slog.New(aelog.NewHandler(buf, nil, nil))
+		log.InfoContext(aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "abc"}), "info")
+		got := parseRecords(t, buf)
+		if len(got) != 1 {
+			t.Fatalf("got %d records, want one", len(got))
+		}
+		if got, want := got[0]["logging.googleapis.com/trace"], "projects/meta/traces/abc"; got != want {
+			t.Errorf("trace: got %v, want %q", got, want)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("metadata server received %d requests, want one", got)
+	}
+}
+
+func TestOptions_Labels(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{Labels: map[string]string{"service": "api", "env": "prod"}}))
+	log.Info("info")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity":                      "INFO",
+		"message":                       "info",
+		"logging.googleapis.com/labels": map[string]any{"service": "api", "env": "prod"},
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestOptions_Labels_cloudRun(t *testing.T) {
+	t.Setenv("K_SERVICE", "run")
+	t.Setenv("K_REVISION", "run-001")
+	t.Setenv("K_CONFIGURATION", "")
+
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{Labels: map[string]string{"revision_name": "explicit"}}))
+	log.Info("info")
+	log.Error("error")
+
+	// Labels detected from the environment are only added to errors.
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{
+			"severity":                      "INFO",
+			"message":                       "info",
+			"logging.googleapis.com/labels": map[string]any{"revision_name": "explicit"},
+		},
+		{
+			"severity":                      "ERROR",
+			"message":                       "error",
+			"logging.googleapis.com/labels": map[string]any{"service_name": "run", "revision_name": "explicit"},
+		},
+	}
+	if diff := cmp.Diff(got, want, ignoreFields(aelog.TimeKey, aelog.ServiceContextKey)); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestOptions_Labels_appEngine(t *testing.T) {
+	t.Setenv("GAE_INSTANCE", "00c61b117c")
+	t.Setenv("GAE_DEPLOYMENT_ID", "123456")
+	t.Setenv("GAE_RUNTIME", "go123")
+
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{EnvironmentFields: true}))
+	log.Info("info")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity": "INFO",
+		"message":  "info",
+		"logging.googleapis.com/labels": map[string]any{
+			"instance_id":   "00c61b117c",
+			"deployment_id": "123456",
+			"runtime":       "go123",
+		},
+	}}
+	if diff := cmp.Diff(got, want, ignoreFields(aelog.TimeKey, aelog.ServiceContextKey)); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestOptions_KubernetesLabels(t *testing.T) {
+	t.Setenv("POD_NAME", "web-7d4b9c-x2x8z")
+	t.Setenv("POD_NAMESPACE", "prod")
+	t.Setenv("NODE_NAME", "gke-node-1")
+	t.Setenv("CONTAINER_NAME", "")
+
+	for _, tc := range []struct {
+		name string
+		opts *aelog.Options
+		want any
+	}{
+		{
+			name: "disabled",
+			opts: nil,
+			want: nil,
+		},
+		{
+			name: "enabled",
+			opts: &aelog.Options{KubernetesLabels: true, Labels: map[string]string{"namespace_name": "explicit"}},
+			want: map[string]any{
+				"pod_name":       "web-7d4b9c-x2x8z",
+				"namespace_name": "explicit",
+				"node_name":      "gke-node-1",
+			},
+		},
+	} {
+		buf := new(bytes.Buffer)
+		slog.New(aelog.NewHandler(buf, nil, tc.opts)).Info("info")
+		got := parseRecords(t, buf)
+		if len(got) != 1 {
+			t.Fatalf("%s: got %d records, want one", tc.name, len(got))
+		}
+		if diff := cmp.Diff(got[0][aelog.LabelsKey], tc.want); diff != "" {
+			t.Errorf("%s: -got +want %s", tc.name, diff)
+		}
+	}
+}
+
+func TestOptions_ServiceContext(t *testing.T) {
+	for _, name := range []string{"GAE_SERVICE", "GAE_VERSION"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("K_SERVICE", "run")
+	t.Setenv("K_REVISION", "run-001")
+
+	for _, tc := range []struct {
+		name string
+		opts *aelog.Options
+		log  func(*slog.Logger)
+		want any
+	}{
+		{
+			name: "detected",
+			log:  func(l *slog.Logger) { l.Info("info") },
+			want: nil,
+		},
+		{
+			name: "detected error",
+			log:  func(l *slog.Logger) { l.Error("error") },
+			want: map[string]any{"service": "run", "version": "run-001"},
+		},
+		{
+			name: "EnvironmentFields",
+			opts: &aelog.Options{EnvironmentFields: true},
+			log:  func(l *slog.Logger) { l.Info("info") },
+			want: map[string]any{"service": "run", "version": "run-001"},
+		},
+		{
+			name: "explicit",
+			opts: &aelog.Options{ServiceContext: aelog.ServiceContext{Service: "svc"}},
+			log:  func(l *slog.Logger) { l.Info("info") },
+			want: map[string]any{"service": "svc"},
+		},
+		{
+			name: "With",
+			log: func(l *slog.Logger) {
+				l.With(slog.Group(aelog.ServiceContextKey, "service", "with")).Info("info")
+			},
+			want: map[string]any{"service": "with"},
+		},
+		{
+			name: "record",
+			log: func(l *slog.Logger) {
+				l.Info("info", slog.Group(aelog.ServiceContextKey, "service", "record", "version", "v2"))
+			},
+			want: map[string]any{"service": "record", "version": "v2"},
+		},
+	} {
+		buf := new(bytes.Buffer)
+		tc.log(slog.New(aelog.NewHandler(buf, nil, tc.opts)))
+		got := parseRecords(t, buf)
+		if len(got) != 1 {
+			t.Fatalf("%s: got %d records, want one", tc.name, len(got))
+		}
+		if diff := cmp.Diff(got[0][aelog.ServiceContextKey], tc.want); diff != "" {
+			t.Errorf("%s: -got +want %s", tc.name, diff)
+		}
+	}
+}
+
+func TestHandler_LevelVar(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warning")
+	buf := new(bytes.Buffer)
+	h := aelog.NewHandler(buf, nil, nil)
+	log := slog.New(h).With("a", 1)
+	log.Info("hidden")
+	log.Warn("warning")
+	h.LevelVar().Set(aelog.LevelDebug)
+	log.Debug("debug")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{"severity": "WARNING", "message": "warning", "a": 1.0},
+		{"severity": "DEBUG", "message": "debug", "a": 1.0},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+
+	v := new(slog.LevelVar)
+	if got := aelog.NewHandler(buf, nil, &aelog.Options{LevelVar: v}).LevelVar(); got != v {
+		t.Errorf("LevelVar: got %p, want %p", got, v)
+	}
+	if got := aelog.NewHandler(buf, &slog.HandlerOptions{Level: aelog.LevelWarn}, nil).LevelVar(); got != nil {
+		t.Errorf("LevelVar: got %v, want nil", got)
+	}
+}
+
+func TestOptions_ErrorReporting(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{ErrorReporting: true}))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		log.WarnContext(r.Context(), "warning")
+		log.ErrorContext(r.Context(), "error")
+	}
+	req := httptest.NewRequest(http.MethodPost, "/path", nil)
+	req.Header.Set("User-Agent", "test")
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	got := parseRecords(t, buf)
+	if len(got) != 2 {
+		t.Fatalf("got %d records, want two", len(got))
+	}
+	if typ, ok := got[0]["@type"]; ok {
+		t.Errorf("WARNING record has type %v", typ)
+	}
+	if got, want := got[1]["@type"], aelog.ErrorEventType; got != want {
+		t.Errorf("@type: got %v, want %q", got, want)
+	}
+	errCtx, _ := got[1]["context"].(map[string]any)
+	wantRequest := map[string]any{
+		"method":    "POST",
+		"url":       "/path",
+		"userAgent": "test",
+		"remoteIp":  "192.0.2.1:1234",
+	}
+	if diff := cmp.Diff(errCtx["httpRequest"], wantRequest); diff != "" {
+		t.Error("context.httpRequest: -got +want", diff)
+	}
+	loc, _ := errCtx["reportLocation"].(map[string]any)
+	if got, want := loc["functionName"], "github.com/phst/aelog_test.TestOptions_ErrorReporting.func1"; got != want {
+		t.Errorf("context.reportLocation.functionName: got %v, want %q", got, want)
+	}
+}
+
+func TestOptions_AddStackTrace(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{AddStackTrace: aelog.LevelError}))
+	log.Warn("warning")
+	log.Error("error")
+	log.Error("explicit", aelog.StackTraceKey, "stack")
+
+	got := parseRecords(t, buf)
+	if len(got) != 3 {
+		t.Fatalf("got %d records, want three", len(got))
+	}
+	if stack, ok := got[0][aelog.StackTraceKey]; ok {
+		t.Errorf("WARNING record has stack trace %v", stack)
+	}
+	stack, _ := got[1][aelog.StackTraceKey].(string)
+	if !strings.HasPrefix(stack, "goroutine ") {
+		t.Errorf("ERROR record has invalid stack trace %q", stack)
+	}
+	// The stack trace should start at the logging call.
+	_, frames, _ := strings.Cut(stack, "\n")
+	if want := "github.com/phst/aelog_test.TestOptions_AddStackTrace("; !strings.HasPrefix(frames, want) {
+		t.Errorf("stack trace %q doesn’t start with %q", stack, want)
+	}
+	if got, want := got[2][aelog.StackTraceKey], "stack"; got != want {
+		t.Errorf("explicit stack trace: got %v, want %q", got, want)
+	}
+}
+
+func TestHandler_concurrent(t *testing.T) {
+	w := new(slowWriter)
+	h := aelog.NewHandler(w, nil, &aelog.Options{
+		Routes: []aelog.Route{{
+			Match:  func(_ context.Context, r slog.Record) bool { return r.Level >= aelog.LevelError },
+			Writer: w,
+		}},
+	})
+	var wg sync.WaitGroup
+	for i := range 10 {
+		log := slog.New(h).With("goroutine", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				log.Info("info", "j", j)
+				log.Error("error", "j", j)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := len(parseRecords(t, &w.buf)); got != 200 {
+		t.Errorf("got %d records, want 200", got)
+	}
+}
+
+// slowWriter writes one byte at a time.  It isn’t safe for concurrent use.
+type slowWriter struct{ buf bytes.Buffer }
+
+func (w *slowWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	runtime.Gosched()
+	return w.buf.Write(b[:1])
+}
+
 func TestHandler_generic(t *testing.T) {
 	buf := new(bytes.Buffer)
-	err := slogtest.TestHandler(aelog.NewHandler(buf, nil, nil), func() []map[string]any { return parseRecords(t, buf) })
-	if err != nil {
+	results := func() []map[string]any {
+		recs := parseRecords(t, buf)
+		// slogtest expects the standard keys, so translate them back.
+		for _, m := range recs {
+			for from, to := range map[string]string{
+				aelog.SeverityKey: slog.LevelKey,
+				aelog.MessageKey:  slog.MessageKey,
+			} {
+				if v, ok := m[from]; ok {
+					delete(m, from)
+					m[to] = v
+				}
+			}
+		}
+		return recs
+	}
+	if err := slogtest.TestHandler(aelog.NewHandler(buf, nil, nil), results); err != nil {
 		t.Error(err)
 	}
 }
 
+func BenchmarkHandler(b *testing.B) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "abc/123;o=1")
+	var reqCtx context.Context
+	aelog.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqCtx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, bc := range []struct {
+		name string
+		opts *slog.HandlerOptions
+		ctx  context.Context
+		log  func(context.Context, *slog.Logger)
+	}{
+		{"message", nil, context.Background(), func(ctx context.Context, l *slog.Logger) {
+			l.InfoContext(ctx, "message")
+		}},
+		{"attrs", nil, context.Background(), func(ctx context.Context, l *slog.Logger) {
+			l.InfoContext(ctx, "message", "string", "value", "int", 123, "duration", time.Second, "bool", true)
+		}},
+		{"group", nil, context.Background(), func(ctx context.Context, l *slog.Logger) {
+			l.InfoContext(ctx, "message", slog.Group("group", "a", 1, "b", "two"))
+		}},
+		{"source", &slog.HandlerOptions{AddSource: true}, context.Background(), func(ctx context.Context, l *slog.Logger) {
+			l.InfoContext(ctx, "message", "int", 123)
+		}},
+		{"request", nil, reqCtx, func(ctx context.Context, l *slog.Logger) {
+			l.InfoContext(ctx, "message", "int", 123)
+		}},
+		{"with", nil, context.Background(), func(ctx context.Context, l *slog.Logger) {
+			l.InfoContext(ctx, "message", "int", 123)
+		}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			log := slog.New(aelog.NewHandler(io.Discard, bc.opts, &aelog.Options{ProjectID: "test"}))
+			if bc.name == "with" {
+				log = log.With("string", "value", "duration", time.Second).WithGroup("group").With("bool", true)
+			}
+			b.ReportAllocs()
+			for range b.N {
+				bc.log(bc.ctx, log)
+			}
+		})
+	}
+}
+
 func parseRecords(t *testing.T, r io.Reader) (recs []map[string]any) {
 	t.Helper()
 
@@ -281,3 +1175,93 @@ func parseTime(v any) time.Time {
 	}
 	return t
 }
+
+func TestOptions_OmitTime(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts *slog.HandlerOptions
+	}{
+		{"fast", nil},
+		{"ReplaceAttr", &slog.HandlerOptions{ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr { return a }}},
+	} {
+		buf := new(bytes.Buffer)
+		slog.New(aelog.NewHandler(buf, tc.opts, &aelog.Options{OmitTime: true})).Info("info")
+		got := parseRecords(t, buf)
+		want := []map[string]any{{"severity": "INFO", "message": "info"}}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("%s: -got +want %s", tc.name, diff)
+		}
+	}
+}
+
+func TestOptions_TimeFormat(t *testing.T) {
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 123_456_789, time.UTC)
+	far := time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name    string
+		format  aelog.TimeFormat
+		time    time.Time
+		want    any
+		decoded time.Time
+	}{
+		{"RFC3339Nano", aelog.TimeFormatRFC3339Nano, ts, "2025-01-02T03:04:05.123456789Z", ts},
+		{"RFC3339Millis", aelog.TimeFormatRFC3339Millis, ts, "2025-01-02T03:04:05.123Z", ts.Truncate(time.Millisecond)},
+		{"UnixNano", aelog.TimeFormatUnixNano, ts, float64(ts.UnixNano()), ts},
+		{"UnixNano/far future", aelog.TimeFormatUnixNano, far, "3000-01-01T00:00:00Z", far},
+		{"UnixNano/far past", aelog.TimeFormatUnixNano, time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC), "1600-01-01T00:00:00Z", time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		buf := new(bytes.Buffer)
+		h := aelog.NewHandler(buf, nil, &aelog.Options{TimeFormat: tc.format})
+		r := slog.NewRecord(tc.time, aelog.LevelInfo, "info", 0)
+		r.AddAttrs(slog.Time("at", tc.time))
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+		got := parseRecords(t, bytes.NewReader(buf.Bytes()))
+		want := []map[string]any{{
+			aelog.TimeKey: tc.want,
+			"severity":    "INFO",
+			"message":     "info",
+			"at":          tc.want,
+		}}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("%s: -got +want %s", tc.name, diff)
+		}
+		e, err := aelog.ParseEntry(bytes.TrimSpace(buf.Bytes()))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if !e.Time.Equal(tc.decoded) {
+			t.Errorf("%s: decoded time %v, want %v", tc.name, e.Time, tc.decoded)
+		}
+	}
+}
+
+func TestOptions_TimeFormat_zero(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{TimeFormat: aelog.TimeFormatUnixNano}))
+	log.Info("info", slog.Time("at", time.Time{}))
+	got := parseRecords(t, buf)
+	want := []map[string]any{{"severity": "INFO", "message": "info", "at": "0001-01-01T00:00:00Z"}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestOptions_AddSequence(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{AddSequence: true}))
+	log.Info("one")
+	log.With("a", 1).Info("two")
+	log.WithGroup("g").Info("three", "b", 2)
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{"severity": "INFO", "message": "one", aelog.SequenceKey: 1.0},
+		{"severity": "INFO", "message": "two", aelog.SequenceKey: 2.0, "a": 1.0},
+		{"severity": "INFO", "message": "three", aelog.SequenceKey: 3.0, "g": map[string]any{"b": 2.0}},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
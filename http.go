@@ -1,4 +1,4 @@
-// Copyright 2023, 2024 Google LLC
+// Copyright 2023, 2024, 2026 Google LLC
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -16,22 +16,443 @@ package aelog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"runtime/pprof"
+	rtrace "runtime/trace"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // Middleware returns a derived version of the given HTTP handler that calls it
 // after ensuring that a [Handler] can extract HTTP-specific information from
-// HTTP requests.
+// HTTP requests.  It’s equivalent to calling [MiddlewareWithOptions] with nil
+// options.
 func Middleware(h http.Handler) http.Handler {
-	return &middleware{h}
+	return MiddlewareWithOptions(h, nil)
 }
 
-type middleware struct{ h http.Handler }
+// MiddlewareWithOptions is like [Middleware], but can be configured using
+// [MiddlewareOptions].  Passing nil options has the same effect as passing a
+// pointer to a zero struct.
+func MiddlewareWithOptions(h http.Handler, opts *MiddlewareOptions) http.Handler {
+	if opts == nil {
+		opts = new(MiddlewareOptions)
+	}
+	return &middleware{h, *opts}
+}
+
+// MiddlewareOptions contains options for [MiddlewareWithOptions].
+type MiddlewareOptions struct {
+	// If set, call the handler with the [profiler labels] “route” and
+	// “trace” so that CPU profiles can be sliced by the same identifiers
+	// that appear in logs.  The route is the pattern that matched the
+	// request (see [http.Request.Pattern]) if the middleware is installed
+	// below an [http.ServeMux], and the URL path otherwise.  The trace
+	// label is only set if the request is traced.
+	//
+	// [profiler labels]: https://pkg.go.dev/runtime/pprof#Do
+	ProfilerLabels bool
+
+	// If set, create an [execution trace task] for each request, so that
+	// execution traces can be correlated with the log entries of the
+	// request.  The task type is the route as described for
+	// ProfilerLabels; the trace ID is logged to the task in the category
+	// “trace”.  Use [trace.WithRegion] with the request context to add
+	// regions to the task.  While execution tracing is enabled, [Handler]
+	// also logs messages to the execution trace, using the severity as
+	// category.
+	//
+	// [execution trace task]: https://pkg.go.dev/runtime/trace#hdr-User_annotation
+	TraceTasks bool
+
+	// If positive, log a summary record at [LevelWarn] for each request
+	// that takes longer than SlowThreshold, even if the handler didn’t log
+	// anything.  The record contains the HTTP request information, the
+	// route as described for ProfilerLabels, and the latency.
+	SlowThreshold time.Duration
+
+	// If set, log a record at [LevelWarn] for each request whose context
+	// was canceled before the handler returned.  The record’s attribute
+	// [CancellationKey] distinguishes between clients that went away
+	// (“client_disconnected”) and exceeded deadlines
+	// (“deadline_exceeded”).  Such events are otherwise invisible in the
+	// logs.
+	LogCancellation bool
+
+	// If set, wrap the [http.ResponseWriter] passed to the handler to
+	// capture the response status and size.  Once the handler has started
+	// the response, the HTTP request information of subsequent records
+	// contains the status and response size.  For requests that result in
+	// a server error (status 5xx), the middleware then logs a record at
+	// [LevelError] unless something else has already logged a record at
+	// [LevelError] or above for the request.  This ensures that handlers
+	// that only call [http.Error] still trigger alerting.
+	CaptureResponse bool
+
+	// If set, log a record at [LevelInfo] when a request starts and
+	// another one when it ends.  This helps diagnosing requests that hang
+	// and never finish.  The end record contains the latency, and the
+	// response status if CaptureResponse is set.  If CaptureResponse is
+	// set and the handler has written a response, the end record also
+	// contains the time to first byte (“timeToFirstByte”) and the time
+	// from the first byte until the handler returned (“streamDuration”),
+	// to distinguish slow responses from long-lived streams.
+	LogRequests bool
+
+	// If set, log a single access record for each request after the
+	// handler has returned, similar to the request logs of the App Engine
+	// first-generation runtimes.  The record contains the full HTTP
+	// request information including status and latency, and the trace.
+	// Its severity is the highest severity of all records logged for the
+	// request through a [Handler], but at least [LevelInfo].  If the
+	// access records go to a different log than the other records (for
+	// example, because AccessLogger uses a [Handler] with a separate
+	// writer), the Logs Explorer shows the other records of a request
+	// nested under its access record.  AccessLog implies CaptureResponse.
+	AccessLog bool
+
+	// Logger for the access records.  If nil, use Logger.
+	AccessLogger *slog.Logger
+
+	// If set, hold back records below [LevelWarn] that a [Handler] logs
+	// for a request while the handler runs, and only write them if the
+	// request fails, that is, if a record at [LevelWarn] or above is
+	// logged for the request or the response status is 5xx.  Otherwise,
+	// drop them when the handler returns.  This provides full detail for
+	// failed requests without paying for it on every request.  Records
+	// that the middleware itself logs aren’t held back.  TailBuffer
+	// implies CaptureResponse.
+	TailBuffer bool
+
+	// Requests whose URL path is in SkipPaths or for which Skip returns
+	// true bypass the middleware: the middleware passes them unchanged to
+	// the handler.  Use this to reduce noise and cost from health checks,
+	// for example for the paths “/healthz” and “/readyz”.
+	SkipPaths []string
+	Skip      func(*http.Request) bool
+
+	// If set, the middleware still associates skipped requests with the
+	// request information and trace, but doesn’t log the request-level
+	// records controlled by SlowThreshold, LogCancellation, LogRequests,
+	// and AccessLog for them.
+	SkipRecordsOnly bool
+
+	// Determines how much of the Referer header appears in the “referer”
+	// field of the HTTP request information.  The default policy strips
+	// query strings, which often contain session tokens.  The middleware
+	// never logs cookies or other request headers.
+	RefererPolicy RefererPolicy
+
+	// Determines what happens if the handler panics.  By default, the
+	// middleware doesn’t intervene, and the HTTP server logs the panic in
+	// an unstructured format.
+	PanicPolicy PanicPolicy
+
+	// If set, create a request-scoped logger that [RequestLogger] and
+	// [FromContext] return.  The logger’s records are correlated with the
+	// request even if they are logged using methods without a context
+	// argument, such as [slog.Logger.Info].  The logger is based on Logger
+	// if set, and otherwise on the logger that [FromContext] returns for
+	// the incoming request context, so that loggers stored using
+	// [NewContext] by outer middlewares are preserved.
+	RequestLogger bool
+
+	// Logger for the records that the middleware itself logs.  It should
+	// use a [Handler] so that the records contain the HTTP request
+	// information.  If nil, the middleware uses [slog.Default].
+	Logger *slog.Logger
+}
+
+type middleware struct {
+	h    http.Handler
+	opts MiddlewareOptions
+}
 
 func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.skip(r) {
+		if !m.opts.SkipRecordsOnly {
+			m.h.ServeHTTP(w, r)
+			return
+		}
+		q := *m
+		q.opts.SlowThreshold = 0
+		q.opts.LogCancellation = false
+		q.opts.LogRequests = false
+		q.opts.AccessLog = false
+		m = &q
+	}
+	start := time.Now()
+	trace := requestTrace(r.Header)
+	info := &httpInfo{req: requestValue(r, m.opts.RefererPolicy)}
+	if m.opts.CaptureResponse || m.opts.AccessLog || m.opts.TailBuffer {
+		info.resp = &responseWriter{ResponseWriter: w}
+		w = info.resp
+	}
+	ctx := context.WithValue(r.Context(), httpInfoKey, info)
+	ctx = ContextWithTrace(ctx, trace)
+	if m.opts.TraceTasks {
+		var task *rtrace.Task
+		ctx, task = rtrace.NewTask(ctx, requestRoute(r))
+		defer task.End()
+		if trace.ID != "" {
+			rtrace.Log(ctx, "trace", trace.ID)
+		}
+	}
+	if m.opts.RequestLogger {
+		base := m.opts.Logger
+		if base == nil {
+			base = FromContext(ctx)
+		}
+		info.logger = slog.New(&boundHandler{base.Handler(), ctx})
+		ctx = NewContext(ctx, info.logger)
+	}
+	if m.opts.LogRequests {
+		m.logger().LogAttrs(ctx, LevelInfo, "request started")
+	}
+	if m.opts.TailBuffer {
+		info.tail = new(tailBuffer)
+	}
+	// Log the request-level records in a deferred call so that they are
+	// logged even if the handler panics and PanicPolicy doesn’t recover.
+	defer func() { m.finish(r, info, start) }()
+	if m.opts.ProfilerLabels {
+		labels := []string{"route", requestRoute(r)}
+		if trace.ID != "" {
+			labels = append(labels, "trace", trace.ID)
+		}
+		pprof.Do(ctx, pprof.Labels(labels...), func(ctx context.Context) {
+			r = r.WithContext(ctx)
+			m.serve(w, r)
+		})
+	} else {
+		r = r.WithContext(ctx)
+		m.serve(w, r)
+	}
+}
+
+// finish logs the request-level records after the handler has returned or
+// panicked.  r is the request that the middleware passed to the handler.  If
+// the handler is an http.ServeMux, it has filled in r.Pattern.
+func (m *middleware) finish(r *http.Request, info *httpInfo, start time.Time) {
+	elapsed := time.Since(start)
+	info.latency.Store(int64(elapsed))
+	if info.tail != nil {
+		info.tail.finish(r.Context(), info.resp.statusCode() >= 500)
+	}
+	if err := r.Context().Err(); err != nil && m.opts.LogCancellation {
+		m.logCancellation(r.Context(), err)
+	}
+	if resp := info.resp; resp != nil && resp.statusCode() >= 500 && !info.errorLogged.Load() {
+		m.logger().LogAttrs(r.Context(), LevelError, "server error", slog.Int("status", resp.statusCode()))
+	}
+	if t := m.opts.SlowThreshold; t > 0 && elapsed > t {
+		m.logger().LogAttrs(
+			r.Context(), LevelWarn, "slow request",
+			slog.String("route", requestRoute(r)),
+			slog.String("latency", FormatDuration(elapsed)),
+		)
+	}
+	if m.opts.LogRequests {
+		attrs := []slog.Attr{slog.String("latency", FormatDuration(elapsed))}
+		if resp := info.resp; resp != nil {
+			attrs = append(attrs, slog.Int("status", resp.statusCode()))
+			if t := resp.started(); !t.IsZero() {
+				ttfb := t.Sub(start)
+				attrs = append(
+					attrs,
+					slog.String("timeToFirstByte", FormatDuration(ttfb)),
+					slog.String("streamDuration", FormatDuration(elapsed-ttfb)),
+				)
+			}
+		}
+		m.logger().LogAttrs(r.Context(), LevelInfo, "request finished", attrs...)
+	}
+	if m.opts.AccessLog {
+		l := m.opts.AccessLogger
+		if l == nil {
+			l = m.logger()
+		}
+		msg := fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, info.resp.statusCode())
+		l.LogAttrs(r.Context(), max(LevelInfo, slog.Level(info.maxLevel.Load())), msg)
+	}
+}
+
+// skip returns whether the middleware should skip the given request.  See
+// MiddlewareOptions.SkipPaths.
+func (m *middleware) skip(r *http.Request) bool {
+	return slices.Contains(m.opts.SkipPaths, r.URL.Path) || m.opts.Skip != nil && m.opts.Skip(r)
+}
+
+// serve calls the wrapped handler, applying the panic policy.
+func (m *middleware) serve(w http.ResponseWriter, r *http.Request) {
+	if m.opts.PanicPolicy != PanicIgnore {
+		defer m.recoverPanic(w, r)
+	}
+	m.h.ServeHTTP(w, r)
+}
+
+// recoverPanic implements PanicRecover and PanicRepanic.  It must be called
+// directly as a deferred function.
+func (m *middleware) recoverPanic(w http.ResponseWriter, r *http.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		// This panic is only used to abort the response; the HTTP
+		// server suppresses it.
+		panic(v)
+	}
+	logPC(r.Context(), m.logger(), LevelCritical, panicPC(), fmt.Sprint("panic: ", v), StackTraceKey, panicStack(v))
+	if m.opts.PanicPolicy == PanicRepanic {
+		panic(v)
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// PanicPolicy determines what the middleware does if the handler panics.  See
+// [MiddlewareOptions.PanicPolicy].
+type PanicPolicy int
+
+const (
+	// Don’t recover from panics.  This is the default.
+	PanicIgnore PanicPolicy = iota
+
+	// Recover from the panic, log the panic value and a stack trace at
+	// [LevelCritical], and respond with status 500.  If the handler has
+	// already started the response, the status can’t be changed anymore.
+	PanicRecover
+
+	// Log the panic value and a stack trace at [LevelCritical], and then
+	// panic again with the same value.  The middleware still logs the
+	// request-level records such as those for LogRequests and AccessLog.
+	PanicRepanic
+)
+
+// CancellationKey is the key of the attribute that describes why a request
+// was canceled.  See [MiddlewareOptions.LogCancellation].
+const CancellationKey = "cancellation"
+
+func (m *middleware) logCancellation(ctx context.Context, err error) {
+	var reason string
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		reason = "deadline_exceeded"
+	case errors.Is(err, context.Canceled):
+		// The HTTP server cancels the request context if the client
+		// closes the connection.
+		reason = "client_disconnected"
+	default:
+		reason = "unknown"
+	}
+	attrs := []slog.Attr{slog.String(CancellationKey, reason)}
+	if cause := context.Cause(ctx); cause != nil && cause != err {
+		attrs = append(attrs, slog.String("cause", cause.Error()))
+	}
+	m.logger().LogAttrs(ctx, LevelWarn, "request canceled", attrs...)
+}
+
+func (m *middleware) logger() *slog.Logger {
+	if l := m.opts.Logger; l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// RefererPolicy determines how the middleware logs the Referer header.  See
+// [MiddlewareOptions.RefererPolicy].  All policies remove user names and
+// passwords from the URL.
+type RefererPolicy int
+
+const (
+	// Log the referring URL without query string, fragment, and path
+	// parameters (such as “;jsessionid=…”).  This is the default.
+	RefererNoQuery RefererPolicy = iota
+
+	// Log only the scheme and host of the referring URL.
+	RefererOrigin
+
+	// Don’t log the Referer header at all.
+	RefererNone
+
+	// Log the full referring URL except for user names and passwords.
+	RefererFull
+)
+
+// scrub returns the referer to log according to the policy.  It returns an
+// empty string if nothing should be logged.
+func (p RefererPolicy) scrub(ref string) string {
+	if ref == "" || p == RefererNone {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		// We can’t reliably remove sensitive parts from an invalid
+		// URL.
+		return ""
+	}
+	u.User = nil
+	switch p {
+	case RefererOrigin:
+		return (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+	case RefererFull:
+		return u.String()
+	default:
+		u.RawQuery = ""
+		u.ForceQuery = false
+		u.Fragment = ""
+		u.RawFragment = ""
+		if before, _, found := strings.Cut(u.Path, ";"); found {
+			u.Path = before
+			u.RawPath = ""
+		}
+		return u.String()
+	}
+}
+
+// FormatDuration formats a duration in the JSON format for the protocol
+// buffer type google.protobuf.Duration, e.g., “1.5s”.  This is the format
+// of the latency in [HttpRequest], and packages that log durations in a
+// similar way, such as the latency of outgoing calls, should use it as well.
+//
+// [HttpRequest]: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+func FormatDuration(d time.Duration) string {
+	var b [32]byte
+	return string(append(strconv.AppendFloat(b[:0], d.Seconds(), 'f', -1, 64), 's'))
+}
+
+// requestRoute returns the route of an HTTP request for use in profiler
+// labels and similar.
+func requestRoute(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	return r.URL.Path
+}
+
+// Request returns an attribute with the key “httpRequest” that describes r in
+// the format of the [HttpRequest] structure, in the same way as [Middleware]
+// with the default [RefererPolicy].  This is useful for records about a
+// request that are logged outside of the middleware chain, for example about
+// outgoing requests or in tests.  Such records shouldn’t use a context
+// returned by [Middleware], since [Handler] would then add a second
+// “httpRequest” field.
+//
+// [HttpRequest]: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+func Request(r *http.Request) slog.Attr {
+	return slog.Attr{Key: "httpRequest", Value: requestValue(r, RefererNoQuery)}
+}
+
+// requestValue returns the group value describing r for Request and the
+// middleware.
+func requestValue(r *http.Request, policy RefererPolicy) slog.Value {
 	// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
 	attrs := []slog.Attr{
 		slog.String("requestMethod", r.Method),
@@ -44,37 +465,140 @@ func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if ua := r.UserAgent(); ua != "" {
 		attrs = append(attrs, slog.String("userAgent", ua))
 	}
-	if ref := r.Referer(); ref != "" {
+	if ref := policy.scrub(r.Referer()); ref != "" {
 		attrs = append(attrs, slog.String("referer", ref))
 	}
-	// https://cloud.google.com/trace/docs/setup#force-trace
-	s, _, _ := strings.Cut(r.Header.Get("X-Cloud-Trace-Context"), ";")
-	trace, span, _ := strings.Cut(s, "/")
-	ctx := context.WithValue(r.Context(), httpInfoKey, &httpInfo{slog.GroupValue(attrs...), trace, span})
-	m.h.ServeHTTP(w, r.WithContext(ctx))
+	return slog.GroupValue(attrs...)
 }
 
-func httpAttrs(ctx context.Context, projectID string) []slog.Attr {
-	i, ok := ctx.Value(httpInfoKey).(*httpInfo)
-	if !ok || i == nil {
-		return nil
+// appendHTTPAttrs appends the attributes for the HTTP request and the trace
+// to attrs and returns the extended slice.  An empty trace ID means there’s
+// no trace.
+func appendHTTPAttrs(attrs []slog.Attr, ctx context.Context, projectID string, t Trace) []slog.Attr {
+	if req, ok := HTTPRequestFromContext(ctx); ok {
+		attrs = append(attrs, slog.Attr{Key: "httpRequest", Value: req})
 	}
-	attrs := []slog.Attr{{Key: "httpRequest", Value: i.req}}
 	// If we don’t have a project ID, we couldn’t format the trace in the
 	// required format, so bail out.
-	if projectID != "" && i.trace != "" {
-		traceID := fmt.Sprintf("projects/%s/traces/%s", projectID, i.trace)
+	if t.ID != "" && projectID != "" {
+		traceID := "projects/" + projectID + "/traces/" + t.ID
 		attrs = append(attrs, slog.String("logging.googleapis.com/trace", traceID))
-		if i.span != "" {
-			attrs = append(attrs, slog.String("logging.googleapis.com/spanId", i.span))
+		if t.SpanID != "" {
+			attrs = append(attrs, slog.String("logging.googleapis.com/spanId", t.SpanID))
+		}
+		if t.Sampled {
+			attrs = append(attrs, slog.Bool("logging.googleapis.com/trace_sampled", true))
 		}
 	}
 	return attrs
 }
 
+// HTTPRequestFromContext returns the description of the incoming HTTP request
+// that [Middleware] has associated with the given context.  The value is a
+// group value in the format of the [HttpRequest] structure, which [Handler]
+// adds to each record under the key “httpRequest”.  HTTPRequestFromContext
+// returns false if the context doesn’t belong to an incoming request.
+//
+// [HttpRequest]: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+func HTTPRequestFromContext(ctx context.Context) (slog.Value, bool) {
+	i := infoFromContext(ctx)
+	if i == nil {
+		return slog.Value{}, false
+	}
+	return i.request(), true
+}
+
+// request returns the HTTP request information, including response
+// information if available.
+func (i *httpInfo) request() slog.Value {
+	var status int
+	var size int64
+	started := false
+	if i.resp != nil {
+		status, size, started = i.resp.response()
+	}
+	latency := time.Duration(i.latency.Load())
+	if !started && latency == 0 {
+		return i.req
+	}
+	attrs := slices.Clip(i.req.Group())
+	if started {
+		attrs = append(
+			attrs,
+			slog.Int("status", status),
+			// The LogEntry protocol buffer uses an int64 field, which
+			// is serialized as a string.
+			slog.String("responseSize", strconv.FormatInt(size, 10)),
+		)
+	}
+	if latency > 0 {
+		attrs = append(attrs, slog.String("latency", FormatDuration(latency)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// markLogged records that a record at the given level was logged for the
+// current request.
+func markLogged(ctx context.Context, l slog.Level) {
+	i := infoFromContext(ctx)
+	if i == nil {
+		return
+	}
+	if l >= LevelError {
+		i.errorLogged.Store(true)
+	}
+	for {
+		old := i.maxLevel.Load()
+		if int64(l) <= old || i.maxLevel.CompareAndSwap(old, int64(l)) {
+			break
+		}
+	}
+}
+
+// ContextWithHTTPRequest returns a derived context that associates log
+// records with an incoming request.  req must be a group value in the format
+// of the [HttpRequest] structure; [HTTPRequestFromContext] returns it
+// unchanged.  [Middleware] does this automatically for HTTP requests; custom
+// instrumentations for other kinds of servers can use ContextWithHTTPRequest
+// together with [ContextWithTrace] so that [Handler] correlates log records
+// with the request.
+//
+// [HttpRequest]: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+func ContextWithHTTPRequest(ctx context.Context, req slog.Value) context.Context {
+	return context.WithValue(ctx, httpInfoKey, &httpInfo{req: req})
+}
+
+func infoFromContext(ctx context.Context) *httpInfo {
+	i, _ := ctx.Value(httpInfoKey).(*httpInfo)
+	return i
+}
+
 type httpInfo struct {
-	req         slog.Value
-	trace, span string
+	req slog.Value
+
+	// Response writer that captures the response; nil if
+	// MiddlewareOptions.CaptureResponse is false.
+	resp *responseWriter
+
+	// Total latency in nanoseconds once the handler has returned, zero
+	// before.
+	latency atomic.Int64
+
+	// Request-scoped logger; nil if MiddlewareOptions.RequestLogger is
+	// false.
+	logger *slog.Logger
+
+	// Whether a record at LevelError or above was logged for the
+	// request.
+	errorLogged atomic.Bool
+
+	// Buffer for records held back while the handler runs; nil if
+	// MiddlewareOptions.TailBuffer is false.
+	tail *tailBuffer
+
+	// Highest level of the records logged for the request, but at least
+	// zero (LevelInfo).
+	maxLevel atomic.Int64
 }
 
 // See the comments for context.Context.Value.
@@ -0,0 +1,73 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import "log/slog"
+
+// Label returns an attribute that the [Handler] adds to the
+// [labels of the log entry] instead of the JSON payload.  Labels are indexed
+// and cheaper to filter on than payload fields.  Label attributes work in
+// calls to logging functions and in [slog.Logger.With], even within groups,
+// but not within group values.  Labels for the record override labels of the
+// same key from [slog.Logger.With], which override [Options.Labels].  Other
+// handlers treat a Label attribute like a string attribute.
+//
+// [labels of the log entry]: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#FIELDS.labels
+func Label(key, value string) slog.Attr {
+	return slog.Any(key, labelValue(value))
+}
+
+// labelValue is the value of an attribute returned by Label.  Implementing
+// slog.LogValuer ensures that other handlers treat it like a string.
+type labelValue string
+
+func (v labelValue) LogValue() slog.Value { return slog.StringValue(string(v)) }
+
+// labelAttrs returns the labels in the given attribute and true if the
+// attribute is a label attribute or, at the top level, a group with the key
+// LabelsKey.  Otherwise, it returns false.
+func labelAttrs(a slog.Attr, topLevel bool) ([]slog.Attr, bool) {
+	switch v := a.Value; {
+	case v.Kind() == slog.KindLogValuer:
+		if l, ok := v.Any().(labelValue); ok {
+			return []slog.Attr{slog.String(a.Key, string(l))}, true
+		}
+	case topLevel && a.Key == LabelsKey && v.Kind() == slog.KindGroup:
+		var labels []slog.Attr
+		for _, m := range v.Group() {
+			labels = append(labels, slog.String(m.Key, m.Value.Resolve().String()))
+		}
+		return labels, true
+	}
+	return nil, false
+}
+
+// mergeLabels returns a new slice containing the labels from base, replaced
+// or extended by the labels in add.
+func mergeLabels(base []slog.Attr, add []slog.Attr) []slog.Attr {
+	r := make([]slog.Attr, len(base), len(base)+len(add))
+	copy(r, base)
+outer:
+	for _, a := range add {
+		for i, b := range r {
+			if b.Key == a.Key {
+				r[i] = a
+				continue outer
+			}
+		}
+		r = append(r, a)
+	}
+	return r
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestLabel(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{Labels: map[string]string{"env": "prod", "service": "api"}}))
+	log = log.With(aelog.Label("tenant", "acme"), "a", 1, slog.Group(aelog.LabelsKey, "region", "eu"))
+	log.WithGroup("g").Info("info", aelog.Label("service", "worker"), "b", 2)
+	log.Info("other", aelog.Label("tenant", "other"))
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{
+			"severity": "INFO",
+			"message":  "info",
+			"logging.googleapis.com/labels": map[string]any{
+				"env":     "prod",
+				"service": "worker",
+				"tenant":  "acme",
+				"region":  "eu",
+			},
+			"a": 1.0,
+			"g": map[string]any{"b": 2.0},
+		},
+		{
+			"severity": "INFO",
+			"message":  "other",
+			"logging.googleapis.com/labels": map[string]any{
+				"env":     "prod",
+				"service": "api",
+				"tenant":  "other",
+				"region":  "eu",
+			},
+			"a": 1.0,
+		},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestLabel_otherHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	slog.New(slog.NewJSONHandler(buf, nil)).Info("info", aelog.Label("tenant", "acme"))
+	got := parseRecords(t, buf)
+	if len(got) != 1 || got[0]["tenant"] != "acme" {
+		t.Errorf("got %v, want string attribute", got)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import "log/slog"
+
+// Lazy returns a value that calls f only when a handler actually encodes the
+// record containing it, i.e., after level checks and sampling.  This makes it
+// possible to log values that are expensive to compute at no cost if the
+// record is filtered out:
+//
+//	log.Debug("state", slog.Any("db", aelog.Lazy(func() slog.Value { return dumpDB() })))
+//
+// If a record goes to several handlers, for example using a [MultiHandler],
+// f can be called more than once.
+func Lazy(f func() slog.Value) slog.Value {
+	return slog.AnyValue(lazyValue(f))
+}
+
+type lazyValue func() slog.Value
+
+// LogValue implements [slog.LogValuer].
+func (f lazyValue) LogValue() slog.Value { return f() }
@@ -0,0 +1,53 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestLazy(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewSamplingHandler(aelog.NewHandler(buf, nil, nil), nil))
+
+	calls := 0
+	value := aelog.Lazy(func() slog.Value {
+		calls++
+		return slog.GroupValue(slog.Int("calls", calls))
+	})
+
+	log.Debug("disabled", "lazy", value)
+	log.Info("dropped by sampling", "lazy", value)
+	log.Warn("logged", "lazy", value)
+
+	if calls != 1 {
+		t.Errorf("got %d calls, want one", calls)
+	}
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity": "WARNING",
+		"message":  "logged",
+		"lazy":     map[string]any{"calls": 1.0},
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2023 Google LLC
+// Copyright 2023, 2026 Google LLC
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -14,7 +14,10 @@
 
 package aelog
 
-import "log/slog"
+import (
+	"log/slog"
+	"strings"
+)
 
 // Additional logging levels corresponding to [Cloud Logging severities].
 //
@@ -34,6 +37,10 @@ const (
 	LevelError = slog.LevelError
 )
 
+// severities lists all severities that severityForLevel can return, in
+// ascending order.
+var severities = []string{"DEBUG", "INFO", "NOTICE", "WARNING", "ERROR", "CRITICAL", "ALERT", "EMERGENCY"}
+
 func severityForLevel(l slog.Level) string {
 	switch {
 	case l <= LevelDebug:
@@ -54,3 +61,31 @@ func severityForLevel(l slog.Level) string {
 		return "EMERGENCY"
 	}
 }
+
+// ParseLevel parses a Cloud Logging severity name such as “WARNING” or
+// “NOTICE” into the corresponding level.  The comparison is
+// case-insensitive.  ParseLevel also accepts the level names understood by
+// [slog.Level.UnmarshalText], such as “WARN” or “INFO+2”.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToUpper(s) {
+	case "DEBUG":
+		return LevelDebug, nil
+	case "INFO":
+		return LevelInfo, nil
+	case "NOTICE":
+		return LevelNotice, nil
+	case "WARNING":
+		return LevelWarn, nil
+	case "ERROR":
+		return LevelError, nil
+	case "CRITICAL":
+		return LevelCritical, nil
+	case "ALERT":
+		return LevelAlert, nil
+	case "EMERGENCY":
+		return LevelEmergency, nil
+	}
+	var l slog.Level
+	err := l.UnmarshalText([]byte(s))
+	return l, err
+}
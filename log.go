@@ -0,0 +1,51 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// logAt logs a record like [slog.Logger.Log].  skip is the number of stack
+// frames between the caller of interest and logAt; it is used to determine
+// the source location.  A nil logger means [slog.Default].  See
+// https://pkg.go.dev/log/slog#hdr-Wrapping_output_methods.
+func logAt(ctx context.Context, l *slog.Logger, level slog.Level, skip int, msg string, args ...any) {
+	var pcs [1]uintptr
+	// Skip runtime.Callers and logAt itself.
+	runtime.Callers(skip+2, pcs[:])
+	logPC(ctx, l, level, pcs[0], msg, args...)
+}
+
+// logPC is like logAt, but uses the given program counter for the source
+// location.
+func logPC(ctx context.Context, l *slog.Logger, level slog.Level, pc uintptr, msg string, args ...any) {
+	if l == nil {
+		l = slog.Default()
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), level, msg, pc)
+	r.Add(args...)
+	// Like slog.Logger, ignore errors from the handler.
+	_ = l.Handler().Handle(ctx, r)
+}
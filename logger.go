@@ -0,0 +1,64 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// NewContext returns a derived context that carries the given logger.  Use
+// [FromContext] to retrieve it.  This allows passing request-scoped loggers
+// through call chains without adding them to every function signature.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger that [NewContext] has stored in the given
+// context.  If there’s no such logger, FromContext returns the request-scoped
+// logger for the incoming request (see [RequestLogger]) if available, and
+// [slog.Default] otherwise.  FromContext never returns nil.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok && l != nil {
+		return l
+	}
+	return RequestLogger(ctx)
+}
+
+// ContextWithAttrs returns a derived context that carries the given
+// attributes in addition to any attributes that the parent context already
+// carries.  [Handler] adds these attributes to every record logged with the
+// context, at the top level and outside of any groups.  This is useful for
+// request-scoped information like user or job IDs that should appear in all
+// records for a request.  [Label] attributes become labels of the log entry,
+// as usual.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, attrsKey, append(slices.Clip(attrsFromContext(ctx)), attrs...))
+}
+
+// attrsFromContext returns the attributes stored by ContextWithAttrs.
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey).([]slog.Attr)
+	return attrs
+}
+
+const (
+	loggerKey contextKey = 7
+	attrsKey  contextKey = 8
+)
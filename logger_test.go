@@ -0,0 +1,62 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestFromContext(t *testing.T) {
+	ctx := context.Background()
+	if got, want := aelog.FromContext(ctx), slog.Default(); got != want {
+		t.Errorf("FromContext(background): got %p, want default logger %p", got, want)
+	}
+	log := slog.New(aelog.NewHandler(new(bytes.Buffer), nil, nil))
+	if got := aelog.FromContext(aelog.NewContext(ctx, log)); got != log {
+		t.Errorf("FromContext: got %p, want %p", got, log)
+	}
+}
+
+func TestContextWithAttrs(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, nil))
+	ctx := aelog.ContextWithAttrs(context.Background(), slog.String("user", "alice"), aelog.Label("job", "import"))
+	ctx = aelog.ContextWithAttrs(ctx, slog.Int("attempt", 2))
+	log.WithGroup("g").InfoContext(ctx, "info", "a", 1)
+	log.Info("background")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{
+			"severity":                      "INFO",
+			"message":                       "info",
+			"logging.googleapis.com/labels": map[string]any{"job": "import"},
+			"user":                          "alice",
+			"attempt":                       2.0,
+			"g":                             map[string]any{"a": 1.0},
+		},
+		{"severity": "INFO", "message": "background"},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+)
+
+// Mask describes sensitive data such as personally identifiable information
+// that [Handler] masks in messages and string values.  See [Options.Masks].
+type Mask struct {
+	// Pattern that matches the sensitive data.  If nil, the handler
+	// passes entire strings to Replace, which then has to find the
+	// sensitive data itself.
+	Pattern *regexp.Regexp
+
+	// Replace returns the replacement for a match of Pattern.  If nil,
+	// the handler replaces matches with “[MASKED]”.  Use [HashMask] to
+	// replace matches with a hash, so that log entries about the same
+	// data can still be correlated.
+	Replace func(s string) string
+}
+
+// Predefined masks for common kinds of sensitive data.  They are heuristics
+// and can have false positives as well as false negatives.
+var (
+	// EmailMask masks email addresses.
+	EmailMask = Mask{Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)}
+
+	// CreditCardMask masks sequences of 13 to 19 digits, optionally
+	// separated by spaces or dashes, that pass the Luhn check.
+	CreditCardMask = Mask{
+		Pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Replace: func(s string) string {
+			if !luhn(s) {
+				return s
+			}
+			return masked
+		},
+	}
+
+	// BearerTokenMask masks bearer tokens as in HTTP Authorization
+	// headers.
+	BearerTokenMask = Mask{Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)}
+)
+
+// masked is the default replacement for matches of a Mask.
+const masked = "[MASKED]"
+
+// HashMask returns a short hash of s.  It’s useful as [Mask.Replace].  The
+// hash isn’t salted, so it doesn’t protect data with few possible values,
+// such as phone numbers, against brute-force attacks.
+func HashMask(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(h[:8])
+}
+
+// mask applies the masks to s.
+func mask(masks []Mask, s string) string {
+	for _, m := range masks {
+		repl := m.Replace
+		if repl == nil {
+			repl = func(string) string { return masked }
+		}
+		switch {
+		case m.Pattern == nil:
+			s = repl(s)
+		case m.Pattern.MatchString(s):
+			// Only call ReplaceAllStringFunc if there’s a match,
+			// because it always allocates.
+			s = m.Pattern.ReplaceAllStringFunc(s, repl)
+		}
+	}
+	return s
+}
+
+// luhn returns whether the digits in s pass the Luhn check.  It ignores all
+// other characters.
+func luhn(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestOptions_Masks(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{Masks: []aelog.Mask{
+		aelog.EmailMask,
+		aelog.CreditCardMask,
+		aelog.BearerTokenMask,
+		{Pattern: regexp.MustCompile(`user-\d+`), Replace: aelog.HashMask},
+		{Replace: strings.ToUpper},
+	}}))
+	log.Info(
+		"mail from alice@example.com",
+		"card", "4111 1111 1111 1111",
+		"order", "4111 1111 1111 1112",
+		"auth", "Bearer abc.def-123",
+		"user", "user-42",
+		"error", errors.New("unknown user bob@example.org"),
+		"int", 123,
+		slog.Group("g", "email", "carol@example.net"),
+	)
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity": "INFO",
+		"message":  "MAIL FROM [MASKED]",
+		"card":     "[MASKED]",
+		"order":    "4111 1111 1111 1112",
+		"auth":     "[MASKED]",
+		"user":     strings.ToUpper(aelog.HashMask("user-42")),
+		"error":    "UNKNOWN USER [MASKED]",
+		"int":      123.0,
+		"g":        map[string]any{"email": "[MASKED]"},
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestHashMask(t *testing.T) {
+	a, b := aelog.HashMask("a"), aelog.HashMask("b")
+	if a != aelog.HashMask("a") {
+		t.Error("HashMask isn’t deterministic")
+	}
+	if a == b {
+		t.Errorf("HashMask(a) = HashMask(b) = %q", a)
+	}
+	if !strings.HasPrefix(a, "sha256:") {
+		t.Errorf("HashMask(a) = %q, want prefix sha256:", a)
+	}
+}
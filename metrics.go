@@ -0,0 +1,112 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"encoding/json"
+	"maps"
+	"sync"
+)
+
+// Metrics counts the log entries that handlers write, so that you can monitor
+// error rates and the health of the logging pipeline.  Set
+// [Options.Metrics] and [SamplingOptions.Metrics] to collect metrics.
+// Metrics implements [expvar.Var], so you can publish it using
+// [expvar.Publish]; to export the metrics to other monitoring systems, use
+// [Metrics.Snapshot].  The zero Metrics is ready to use.  Metrics objects are
+// safe for concurrent use and can be shared between handlers.  They can’t be
+// copied once used.
+type Metrics struct {
+	mu      sync.Mutex
+	records map[string]int64 // severity → number of entries
+	bytes   map[string]int64 // severity → number of bytes
+	dropped int64
+	sampled int64
+}
+
+// MetricsSnapshot contains the values of a [Metrics] object at some point in
+// time.
+type MetricsSnapshot struct {
+	// Number of entries and bytes written successfully per severity.
+	// The severity is the one written to the entry, see
+	// [Options.SeverityFunc].  A [Batch] counts as a single entry.
+	Records map[string]int64 `json:"records"`
+	Bytes   map[string]int64 `json:"bytes"`
+
+	// Number of records that a [Handler] didn’t write because writing
+	// failed, the [LogBudget] was exhausted, or the [Middleware]
+	// discarded them (see [MiddlewareOptions.TailBuffer]).
+	Dropped int64 `json:"dropped"`
+
+	// Number of records that a [SamplingHandler] didn’t pass on.
+	Sampled int64 `json:"sampled"`
+}
+
+// Snapshot returns the current values of the metrics.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return MetricsSnapshot{
+		Records: maps.Clone(m.records),
+		Bytes:   maps.Clone(m.bytes),
+		Dropped: m.dropped,
+		Sampled: m.sampled,
+	}
+}
+
+// String implements [expvar.Var].  It returns the snapshot in JSON format.
+func (m *Metrics) String() string {
+	b, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		// Can’t happen, the snapshot only contains maps and numbers.
+		panic(err)
+	}
+	return string(b)
+}
+
+// written counts a successfully written entry.  m may be nil.
+func (m *Metrics) written(severity string, n int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.records == nil {
+		m.records = make(map[string]int64)
+		m.bytes = make(map[string]int64)
+	}
+	m.records[severity]++
+	m.bytes[severity] += int64(n)
+}
+
+// drop counts n dropped records.  m may be nil.
+func (m *Metrics) drop(n int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped += int64(n)
+}
+
+// sample counts a record dropped by sampling.  m may be nil.
+func (m *Metrics) sample() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sampled++
+}
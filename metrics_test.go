@@ -0,0 +1,109 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestMetrics(t *testing.T) {
+	m := new(aelog.Metrics)
+	buf := new(bytes.Buffer)
+	h := aelog.NewHandler(buf, nil, &aelog.Options{
+		Metrics:   m,
+		LogBudget: &aelog.LogBudget{Records: 3},
+		Routes: []aelog.Route{{
+			Match:  func(_ context.Context, r slog.Record) bool { return r.Message == "fail" },
+			Writer: failingWriter{},
+		}},
+	})
+	log := slog.New(aelog.NewSamplingHandler(h, &aelog.SamplingOptions{Metrics: m}))
+	log.Info("info")
+	log.Info("sampled")
+	log.Warn("warning")
+	log.Error("error")
+	log.Error("fail")
+	log.Warn("suppressed")
+
+	got := m.Snapshot()
+	want := aelog.MetricsSnapshot{
+		Records: map[string]int64{"WARNING": 1, "ERROR": 1},
+		Bytes:   make(map[string]int64),
+		Dropped: 2,
+		Sampled: 2,
+	}
+	for _, line := range bytes.SplitAfter(buf.Bytes(), []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		e, err := aelog.ParseEntry(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want.Bytes[e.Severity] += int64(len(line))
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+
+	var v expvar.Var = m
+	var fromVar aelog.MetricsSnapshot
+	if err := json.Unmarshal([]byte(v.String()), &fromVar); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(fromVar, got); diff != "" {
+		t.Error("expvar: -got +want", diff)
+	}
+}
+
+func TestMetrics_notify(t *testing.T) {
+	m := new(aelog.Metrics)
+	buf := new(bytes.Buffer)
+	h := aelog.NewHandler(buf, nil, &aelog.Options{
+		Metrics: m,
+		Notify:  func([]byte) {},
+		Routes: []aelog.Route{{
+			Match:  func(_ context.Context, r slog.Record) bool { return r.Message == "fail" },
+			Writer: failingWriter{},
+		}},
+	})
+	log := slog.New(h)
+	log.Log(context.Background(), aelog.LevelAlert, "alert")
+	log.Log(context.Background(), aelog.LevelEmergency, "fail")
+
+	got := m.Snapshot()
+	want := aelog.MetricsSnapshot{
+		Records: map[string]int64{"ALERT": 1},
+		Bytes:   map[string]int64{"ALERT": int64(buf.Len())},
+		Dropped: 1,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken") }
@@ -0,0 +1,100 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// NewMultiHandler creates a new [MultiHandler] that sends records to the
+// given destinations.
+func NewMultiHandler(dests ...Destination) *MultiHandler {
+	return &MultiHandler{dests}
+}
+
+// MultiHandler is an [slog.Handler] that sends each record to several
+// destination handlers, each with its own minimum level.  For example, you
+// could send all records to a [Handler] writing to [os.Stderr], but only
+// records at [LevelError] and above to a handler that sends them to an error
+// reporting backend.  Each destination handler sees the original record, so
+// wrapping a [Handler] in a MultiHandler doesn’t interfere with its handling
+// of special keys.
+//
+// Use [NewMultiHandler] to create MultiHandler objects.
+type MultiHandler struct {
+	dests []Destination
+}
+
+// Destination is a destination handler for a [MultiHandler].
+type Destination struct {
+	// Handler that receives the records.  It must not be nil.
+	Handler slog.Handler
+
+	// Minimum level of the records that Handler receives.  If nil, only
+	// the [slog.Handler.Enabled] method of Handler decides.
+	Level slog.Leveler
+}
+
+// Enabled implements [slog.Handler.Enabled].  It reports whether any
+// destination is enabled for the given level.
+func (h *MultiHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, d := range h.dests {
+		if d.enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements [slog.Handler.Handle].  It sends the record to all
+// enabled destinations, even if some of them fail, and returns the errors of
+// all failed destinations joined using [errors.Join].
+func (h *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, d := range h.dests {
+		if d.enabled(ctx, r.Level) {
+			// Handlers may modify the record, so give each
+			// destination its own copy.
+			if err := d.Handler.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements [slog.Handler.WithAttrs].
+func (h *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(d slog.Handler) slog.Handler { return d.WithAttrs(attrs) })
+}
+
+// WithGroup implements [slog.Handler.WithGroup].
+func (h *MultiHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(d slog.Handler) slog.Handler { return d.WithGroup(name) })
+}
+
+func (h *MultiHandler) with(f func(slog.Handler) slog.Handler) *MultiHandler {
+	dests := make([]Destination, len(h.dests))
+	for i, d := range h.dests {
+		dests[i] = Destination{f(d.Handler), d.Level}
+	}
+	return &MultiHandler{dests}
+}
+
+func (d Destination) enabled(ctx context.Context, l slog.Level) bool {
+	return (d.Level == nil || l >= d.Level.Level()) && d.Handler.Enabled(ctx, l)
+}
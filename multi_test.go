@@ -0,0 +1,84 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestMultiHandler(t *testing.T) {
+	all := new(bytes.Buffer)
+	errs := new(bytes.Buffer)
+	log := slog.New(aelog.NewMultiHandler(
+		aelog.Destination{Handler: aelog.NewHandler(all, nil, nil)},
+		aelog.Destination{Handler: aelog.NewHandler(errs, nil, nil), Level: aelog.LevelError},
+	))
+
+	log.Debug("debug")
+	log.With("foo", "bar").Info("info")
+	log.WithGroup("group").Error("error", "attr", 123)
+
+	gotAll := parseRecords(t, all)
+	wantAll := []map[string]any{
+		{"severity": "INFO", "message": "info", "foo": "bar"},
+		{"severity": "ERROR", "message": "error", "group": map[string]any{"attr": 123.0}},
+	}
+	if diff := cmp.Diff(gotAll, wantAll, ignoreTime); diff != "" {
+		t.Error("all: -got +want", diff)
+	}
+
+	gotErrors := parseRecords(t, errs)
+	wantErrors := []map[string]any{
+		{"severity": "ERROR", "message": "error", "group": map[string]any{"attr": 123.0}},
+	}
+	if diff := cmp.Diff(gotErrors, wantErrors, ignoreTime); diff != "" {
+		t.Error("errors: -got +want", diff)
+	}
+}
+
+func TestMultiHandler_errors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err1, err2 := errors.New("first"), errors.New("second")
+	h := aelog.NewMultiHandler(
+		aelog.Destination{Handler: failingHandler{err1}},
+		aelog.Destination{Handler: aelog.NewHandler(buf, nil, nil)},
+		aelog.Destination{Handler: failingHandler{err2}},
+	)
+
+	err := h.Handle(context.Background(), slog.NewRecord(time.Now(), aelog.LevelInfo, "info", 0))
+	if !errors.Is(err, err1) || !errors.Is(err, err2) {
+		t.Errorf("Handle: got error %v, want both destination errors", err)
+	}
+	if got := len(parseRecords(t, buf)); got != 1 {
+		t.Errorf("got %d records, want one", got)
+	}
+}
+
+// failingHandler is an [slog.Handler] whose Handle method always fails.
+type failingHandler struct{ err error }
+
+func (failingHandler) Enabled(context.Context, slog.Level) bool    { return true }
+func (h failingHandler) Handle(context.Context, slog.Record) error { return h.err }
+func (h failingHandler) WithAttrs([]slog.Attr) slog.Handler        { return h }
+func (h failingHandler) WithGroup(string) slog.Handler             { return h }
@@ -0,0 +1,96 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// handleNotify writes the record like output.handle and passes the encoded
+// entry to the notifier.
+func (h *Handler) handleNotify(out output, e entry) error {
+	return out.write(e, h.notifier.notify)
+}
+
+// notifier calls a notification function asynchronously, at most once per
+// interval.  It’s shared between a Handler and all handlers derived from it.
+type notifier struct {
+	f        func([]byte)
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time // guarded by mu
+
+	// Number of notifications in flight, and a channel that is closed
+	// once there are none; guarded by mu.  Handler.Flush waits for them.
+	running int
+	idle    chan struct{}
+}
+
+// notify calls the notification function for the given entry unless it was
+// called recently.  The caller may reuse the entry once notify returns.
+func (n *notifier) notify(entry []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := time.Now()
+	if !n.last.IsZero() && now.Sub(n.last) < n.interval {
+		return
+	}
+	n.last = now
+	// The function runs asynchronously, so give it its own copy.
+	entry = bytes.Clone(entry)
+	if n.running == 0 {
+		n.idle = make(chan struct{})
+	}
+	n.running++
+	go func() {
+		defer n.done()
+		n.f(entry)
+	}()
+}
+
+func (n *notifier) done() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.running--
+	if n.running == 0 {
+		close(n.idle)
+	}
+}
+
+// notifyWaitTimeout is the maximum time that Handler.Flush waits for
+// notifications in flight.
+const notifyWaitTimeout = 5 * time.Second
+
+// wait waits until all notifications in flight have been sent, or until the
+// timeout expires.  It returns false in the latter case.
+func (n *notifier) wait(timeout time.Duration) bool {
+	n.mu.Lock()
+	running, idle := n.running, n.idle
+	n.mu.Unlock()
+	if running == 0 {
+		return true
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-idle:
+		return true
+	case <-t.C:
+		return false
+	}
+}
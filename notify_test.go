@@ -0,0 +1,109 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestOptions_Notify(t *testing.T) {
+	ctx := context.Background()
+
+	notified := make(chan []byte, 10)
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{
+		Notify:         func(entry []byte) { notified <- entry },
+		NotifyInterval: time.Hour,
+	}))
+
+	log.Error("error")
+	log.With("attr", 123).Log(ctx, aelog.LevelAlert, "alert")
+	log.Log(ctx, aelog.LevelEmergency, "emergency") // rate-limited
+
+	var entry []byte
+	select {
+	case entry = <-notified:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no notification")
+	}
+
+	got := parseRecords(t, bytes.NewReader(entry))
+	want := []map[string]any{{
+		"severity": "ALERT",
+		"message":  "alert",
+		"attr":     123.0,
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+
+	if n := len(parseRecords(t, buf)); n != 3 {
+		t.Errorf("got %d records, want 3", n)
+	}
+}
+
+func TestOptions_Notify_fallback(t *testing.T) {
+	notified := make(chan []byte, 1)
+	fallback := new(bytes.Buffer)
+	var writeErrors int
+	log := slog.New(aelog.NewHandler(failingWriter{}, nil, &aelog.Options{
+		Notify:         func(entry []byte) { notified <- entry },
+		FallbackWriter: fallback,
+		OnWriteError:   func(error) { writeErrors++ },
+	}))
+
+	log.Log(context.Background(), aelog.LevelAlert, "alert")
+
+	got := parseRecords(t, fallback)
+	want := []map[string]any{{"severity": "ALERT", "message": "alert"}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	if writeErrors != 1 {
+		t.Errorf("OnWriteError called %d times, want once", writeErrors)
+	}
+	select {
+	case <-notified:
+	case <-time.After(10 * time.Second):
+		t.Error("no notification")
+	}
+}
+
+func TestOptions_Notify_flush(t *testing.T) {
+	var sent atomic.Bool
+	h := aelog.NewHandler(io.Discard, nil, &aelog.Options{
+		Notify: func([]byte) {
+			time.Sleep(100 * time.Millisecond)
+			sent.Store(true)
+		},
+	})
+	slog.New(h).Log(context.Background(), aelog.LevelAlert, "alert")
+	if err := h.Flush(); err != nil {
+		t.Error("Flush:", err)
+	}
+	if !sent.Load() {
+		t.Error("Flush returned before the notification was sent")
+	}
+}
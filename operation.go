@@ -0,0 +1,92 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// OperationKey is the [special key] for information about a long-running
+// operation that a log entry belongs to.  The Logs Explorer can show all
+// entries of an operation together.  Use [WithOperation] to set it.
+//
+// [special key]: https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+const OperationKey = "logging.googleapis.com/operation"
+
+// WithOperation returns a derived context that associates log records with
+// the given operation.  id identifies the operation; producer optionally
+// identifies the component that produces the operation, for example
+// “github.com/example/app/batch”.  Use [OperationFirst] and [OperationLast]
+// to mark the first and last record of the operation.
+func WithOperation(ctx context.Context, id, producer string) context.Context {
+	return context.WithValue(ctx, operationKey, operation{id, producer})
+}
+
+// OperationFirst returns an attribute that marks the record as the first
+// record of the current operation (see [WithOperation]).
+func OperationFirst() slog.Attr {
+	return slog.Any("operationFirst", operationMarker(true))
+}
+
+// OperationLast returns an attribute that marks the record as the last record
+// of the current operation (see [WithOperation]).
+func OperationLast() slog.Attr {
+	return slog.Any("operationLast", operationMarker(false))
+}
+
+type operation struct {
+	id, producer string
+}
+
+// operationMarker is the value of attributes returned by OperationFirst (true)
+// and OperationLast (false).  Implementing slog.LogValuer ensures that other
+// handlers treat it like a Boolean attribute.
+type operationMarker bool
+
+func (operationMarker) LogValue() slog.Value { return slog.BoolValue(true) }
+
+// isOperationMarker returns whether the attribute was created by
+// OperationFirst or OperationLast, and in that case whether it marks the
+// first record.
+func isOperationMarker(a slog.Attr) (marker, first bool) {
+	if a.Value.Kind() != slog.KindLogValuer {
+		return false, false
+	}
+	m, ok := a.Value.Any().(operationMarker)
+	return ok, bool(m)
+}
+
+// operationAttrs returns the operation attribute for a record, if any.
+func operationAttrs(ctx context.Context, first, last bool) []slog.Attr {
+	op, ok := ctx.Value(operationKey).(operation)
+	if !ok {
+		return nil
+	}
+	// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntryOperation
+	attrs := []slog.Attr{slog.String("id", op.id)}
+	if op.producer != "" {
+		attrs = append(attrs, slog.String("producer", op.producer))
+	}
+	if first {
+		attrs = append(attrs, slog.Bool("first", true))
+	}
+	if last {
+		attrs = append(attrs, slog.Bool("last", true))
+	}
+	return []slog.Attr{{Key: OperationKey, Value: slog.GroupValue(attrs...)}}
+}
+
+const operationKey contextKey = 6
@@ -0,0 +1,71 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestWithOperation(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, nil))
+	ctx := aelog.WithOperation(context.Background(), "job-1", "example/batch")
+	log.InfoContext(ctx, "start", aelog.OperationFirst())
+	log.InfoContext(ctx, "step", "i", 1)
+	log.InfoContext(ctx, "done", aelog.OperationLast())
+	log.Info("unrelated", aelog.OperationFirst())
+
+	violations, err := aelog.Validate(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range violations {
+		t.Errorf("invalid entry: %v", v)
+	}
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{
+			"severity":                         "INFO",
+			"message":                          "start",
+			"logging.googleapis.com/operation": map[string]any{"id": "job-1", "producer": "example/batch", "first": true},
+		},
+		{
+			"severity":                         "INFO",
+			"message":                          "step",
+			"logging.googleapis.com/operation": map[string]any{"id": "job-1", "producer": "example/batch"},
+			"i":                                1.0,
+		},
+		{
+			"severity":                         "INFO",
+			"message":                          "done",
+			"logging.googleapis.com/operation": map[string]any{"id": "job-1", "producer": "example/batch", "last": true},
+		},
+		{
+			"severity": "INFO",
+			"message":  "unrelated",
+		},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
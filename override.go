@@ -0,0 +1,186 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// LevelOverrides holds severity overrides that can be changed at runtime,
+// typically by polling a remote configuration source with
+// [LevelOverrides.Poll].  There’s a global override and overrides for
+// individual components; components are arbitrary names chosen by the
+// program.  Use [LevelOverrides.Leveler] to obtain levelers for handlers:
+//
+//	overrides := &aelog.LevelOverrides{Default: aelog.LevelInfo}
+//	go overrides.Poll(ctx, "https://config.example.com/levels.json", nil)
+//	log := slog.New(aelog.NewHandler(os.Stderr, &slog.HandlerOptions{Level: overrides.Leveler("")}, nil))
+//	dbLog := slog.New(aelog.NewHandler(os.Stderr, &slog.HandlerOptions{Level: overrides.Leveler("db")}, nil))
+//
+// The zero LevelOverrides has no overrides and is ready for use.
+// LevelOverrides objects can’t be copied once in use.
+type LevelOverrides struct {
+	// Level to use if there’s neither a component nor a global override.
+	// If nil, use [LevelInfo].
+	Default slog.Leveler
+
+	cfg atomic.Pointer[levelConfig]
+}
+
+// levelConfig is the parsed form of a level configuration.  It’s immutable
+// once created.
+type levelConfig struct {
+	global     *slog.Level
+	components map[string]slog.Level
+}
+
+// Leveler returns a leveler for the given component.  Its level is the
+// override for the component if there is one, else the global override if
+// there is one, else the default level.  The leveler reflects later updates.
+// Pass an empty component name to only consider the global override.
+func (o *LevelOverrides) Leveler(component string) slog.Leveler {
+	return componentLeveler{o, component}
+}
+
+type componentLeveler struct {
+	o         *LevelOverrides
+	component string
+}
+
+func (l componentLeveler) Level() slog.Level {
+	if c := l.o.cfg.Load(); c != nil {
+		if v, ok := c.components[l.component]; ok && l.component != "" {
+			return v
+		}
+		if c.global != nil {
+			return *c.global
+		}
+	}
+	if l.o.Default != nil {
+		return l.o.Default.Level()
+	}
+	return LevelInfo
+}
+
+// Update atomically replaces all overrides with the ones in the given JSON
+// configuration.  The configuration is a JSON object of the following form:
+//
+//	{"level": "WARNING", "components": {"db": "DEBUG"}}
+//
+// Both fields are optional.  Levels are parsed using [ParseLevel].  If the
+// configuration is invalid, Update returns an error and leaves the overrides
+// unchanged.
+func (o *LevelOverrides) Update(config []byte) error {
+	var raw struct {
+		Level      string            `json:"level"`
+		Components map[string]string `json:"components"`
+	}
+	if err := json.Unmarshal(config, &raw); err != nil {
+		return fmt.Errorf("aelog: invalid level configuration: %w", err)
+	}
+	c := &levelConfig{components: make(map[string]slog.Level, len(raw.Components))}
+	if raw.Level != "" {
+		l, err := ParseLevel(raw.Level)
+		if err != nil {
+			return fmt.Errorf("aelog: invalid global level: %w", err)
+		}
+		c.global = &l
+	}
+	for name, s := range raw.Components {
+		l, err := ParseLevel(s)
+		if err != nil {
+			return fmt.Errorf("aelog: invalid level for component %q: %w", name, err)
+		}
+		c.components[name] = l
+	}
+	o.cfg.Store(c)
+	return nil
+}
+
+// Poll periodically fetches a level configuration from the given HTTP URL
+// and applies it using [LevelOverrides.Update] until the context is canceled.
+// It fetches the configuration once immediately.  Any HTTP endpoint that
+// returns the configuration works, for example a Cloud Storage object if
+// [PollOptions.Client] is authorized to read it.  If fetching or parsing the
+// configuration fails, Poll logs a warning using [slog.Default] and keeps the
+// current overrides.  Passing nil options has the same effect as passing a
+// pointer to a zero struct.  Poll blocks, so you typically want to call it in
+// a separate goroutine.
+func (o *LevelOverrides) Poll(ctx context.Context, url string, opts *PollOptions) {
+	if opts == nil {
+		opts = new(PollOptions)
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	poll := func() {
+		if err := o.fetch(ctx, client, url); err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "can’t update log levels", "url", url, "error", err.Error())
+		}
+	}
+	poll()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			poll()
+		}
+	}
+}
+
+func (o *LevelOverrides) fetch(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("aelog: unexpected HTTP status %s", resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return o.Update(b)
+}
+
+// PollOptions contains options for [LevelOverrides.Poll].
+type PollOptions struct {
+	// HTTP client for fetching the configuration.  If nil, Poll uses
+	// [http.DefaultClient].
+	Client *http.Client
+
+	// Interval between two fetches.  If zero, Poll uses one minute.
+	Interval time.Duration
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/phst/aelog"
+)
+
+func TestLevelOverrides(t *testing.T) {
+	var config atomic.Value
+	config.Store(`{"level": "WARNING", "components": {"db": "debug"}}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(config.Load().(string)))
+	}))
+	defer srv.Close()
+
+	o := &aelog.LevelOverrides{Default: aelog.LevelNotice}
+	global, db, other := o.Leveler(""), o.Leveler("db"), o.Leveler("other")
+	check := func(name string, want ...slog.Level) {
+		t.Helper()
+		for i, l := range []slog.Leveler{global, db, other} {
+			if got := l.Level(); got != want[i] {
+				t.Errorf("%s: leveler %d: got %v, want %v", name, i, got, want[i])
+			}
+		}
+	}
+	check("default", aelog.LevelNotice, aelog.LevelNotice, aelog.LevelNotice)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		o.Poll(ctx, srv.URL, &aelog.PollOptions{Client: srv.Client(), Interval: time.Millisecond})
+	}()
+	waitFor := func(l slog.Leveler, want slog.Level) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); l.Level() != want; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for level %v", want)
+			}
+		}
+	}
+	waitFor(global, aelog.LevelWarn)
+	check("first", aelog.LevelWarn, aelog.LevelDebug, aelog.LevelWarn)
+
+	config.Store(`{"components": {"other": "ERROR"}}`)
+	waitFor(other, aelog.LevelError)
+	check("second", aelog.LevelNotice, aelog.LevelNotice, aelog.LevelError)
+	cancel()
+	<-done
+
+	if err := o.Update([]byte(`{"level": "LOUD"}`)); err == nil {
+		t.Error("Update succeeded for invalid level")
+	}
+	check("invalid", aelog.LevelNotice, aelog.LevelNotice, aelog.LevelError)
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]slog.Level{
+		"DEBUG":     aelog.LevelDebug,
+		"notice":    aelog.LevelNotice,
+		"WARNING":   aelog.LevelWarn,
+		"WARN":      aelog.LevelWarn,
+		"Critical":  aelog.LevelCritical,
+		"EMERGENCY": aelog.LevelEmergency,
+		"INFO+2":    aelog.LevelInfo + 2,
+	} {
+		got, err := aelog.ParseLevel(s)
+		if err != nil {
+			t.Errorf("ParseLevel(%q): %v", s, err)
+		} else if got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", s, got, want)
+		}
+	}
+	if _, err := aelog.ParseLevel("LOUD"); err == nil {
+		t.Error("ParseLevel(LOUD) succeeded")
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// StackTraceKey is the key of the attribute that contains a stack trace.
+// [Error Reporting] recognizes stack traces in this field.
+//
+// [Error Reporting]: https://cloud.google.com/error-reporting/docs/formatting-error-messages
+const StackTraceKey = "stack_trace"
+
+// LogPanic is a last-chance panic logger.  If the current goroutine is
+// panicking, LogPanic logs the panic value and a stack trace at
+// [LevelEmergency] using the default logger, flushes the default handler (see
+// [Fatal]), and then panics again with the same value.  The runtime prints
+// panics in an unstructured format that the platform often truncates;
+// LogPanic ensures that there’s at least one structured record of the panic.
+//
+// LogPanic only works if called directly as a deferred function.  Install it
+// at the top of main and at the top of every goroutine that you start:
+//
+//	func main() {
+//		defer aelog.LogPanic(context.Background())
+//		// …
+//	}
+//
+//	go func() {
+//		defer aelog.LogPanic(ctx)
+//		// …
+//	}()
+func LogPanic(ctx context.Context) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	logPC(ctx, nil, LevelEmergency, panicPC(), fmt.Sprint("panic: ", v), StackTraceKey, panicStack(v))
+	flush(slog.Default().Handler())
+	panic(v)
+}
+
+// panicStack returns a stack trace for the panic value v in the format of the
+// Go runtime.  It must be called from a deferred function.
+func panicStack(v any) string {
+	return fmt.Sprintf("panic: %v\n\n%s", v, debug.Stack())
+}
+
+// callerStack returns a stack trace of the current goroutine in the format of
+// the Go runtime, omitting the frames above the function containing the
+// program counter pc.  If that function isn’t on the stack, callerStack
+// returns the full stack trace.
+func callerStack(pc uintptr) string {
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	stack := string(buf)
+	header, frames, ok := strings.Cut(stack, "\n")
+	if !ok {
+		return stack
+	}
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	prefix := f.Function + "("
+	// Each frame consists of a line with the function and a line with the
+	// file position.
+	for frames != "" {
+		if strings.HasPrefix(frames, prefix) {
+			return header + "\n" + frames
+		}
+		_, frames, _ = strings.Cut(frames, "\n")
+		_, frames, _ = strings.Cut(frames, "\n")
+	}
+	return stack
+}
+
+// panicPC returns the program counter of the function that panicked.  It must
+// be called directly from a deferred function.
+func panicPC() uintptr {
+	var pcs [64]uintptr
+	// Skip runtime.Callers, panicPC, and the deferred function.
+	n := runtime.Callers(3, pcs[:])
+	for _, pc := range pcs[:n] {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			return pc
+		}
+	}
+	return 0
+}
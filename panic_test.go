@@ -0,0 +1,79 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestLogPanic(t *testing.T) {
+	if os.Getenv("AELOG_TEST_PANIC") == "1" {
+		// We’re running in the subprocess.
+		slog.SetDefault(slog.New(aelog.NewHandler(os.Stdout, &slog.HandlerOptions{AddSource: true}, nil)))
+		defer aelog.LogPanic(context.Background())
+		panic("boom")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLogPanic$")
+	cmd.Env = append(os.Environ(), "AELOG_TEST_PANIC=1")
+	stdout := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Fatalf("subprocess didn’t panic: %v", err)
+	}
+
+	// The testing package prints its own messages to stdout, so keep only
+	// the JSON lines.
+	var records bytes.Buffer
+	for _, line := range strings.SplitAfter(stdout.String(), "\n") {
+		if strings.HasPrefix(line, "{") {
+			records.WriteString(line)
+		}
+	}
+	got := parseRecords(t, &records)
+	if len(got) != 1 {
+		t.Fatalf("got %d records, want one", len(got))
+	}
+	stack, ok := got[0][aelog.StackTraceKey].(string)
+	if !ok || !strings.HasPrefix(stack, "panic: boom\n\ngoroutine ") || !strings.Contains(stack, "TestLogPanic") {
+		t.Errorf("invalid stack trace %q", stack)
+	}
+	want := []map[string]any{{
+		"severity": "EMERGENCY",
+		"message":  "panic: boom",
+		"logging.googleapis.com/sourceLocation": map[string]any{
+			"function": "github.com/phst/aelog_test.TestLogPanic",
+		},
+	}}
+	if diff := cmp.Diff(
+		got, want,
+		ignoreFields(aelog.TimeKey, aelog.StackTraceKey, "file", "line"),
+	); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
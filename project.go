@@ -0,0 +1,118 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// projectEnvVars lists the environment variables that envProjectID consults,
+// in order of precedence.
+var projectEnvVars = []string{
+	// https://cloud.google.com/appengine/docs/standard/go/runtime#environment_variables
+	"GOOGLE_CLOUD_PROJECT",
+	// Set by older runtimes and by the Cloud SDK.
+	"GCLOUD_PROJECT",
+	// https://cloud.google.com/functions/docs/configuring/env-var#older_runtimes
+	"GCP_PROJECT",
+	// Used by client libraries for quota and billing; typically set for
+	// local development.
+	"GOOGLE_CLOUD_QUOTA_PROJECT",
+}
+
+// envProjectID attempts to detect the current project ID from the
+// environment variables.  It returns an empty string if that fails; the
+// caller can then fall back to metadataProjectID.
+func envProjectID() string {
+	for _, name := range projectEnvVars {
+		if id := os.Getenv(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// metadataTimeout is the timeout for requests to the metadata server.  Keep
+// it short because outside of Google Cloud, the request typically hangs
+// until it times out.
+const metadataTimeout = 500 * time.Millisecond
+
+// metadataProjects caches the project IDs returned by metadata servers, keyed
+// by host.  The key is normally always the same, but tests use different
+// hosts.
+var metadataProjects struct {
+	mu sync.Mutex
+	m  map[string]*metadataProject
+}
+
+type metadataProject struct {
+	once sync.Once
+	id   string // empty if the lookup failed
+}
+
+// metadataProjectID returns the project ID reported by the metadata server,
+// or an empty string if there’s no metadata server.  It only queries each
+// metadata server once.  See
+// https://cloud.google.com/compute/docs/metadata/predefined-metadata-keys.
+func metadataProjectID() string {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		// Use the IP address to avoid a DNS lookup.
+		host = "169.254.169.254"
+	}
+	metadataProjects.mu.Lock()
+	if metadataProjects.m == nil {
+		metadataProjects.m = make(map[string]*metadataProject)
+	}
+	p := metadataProjects.m[host]
+	if p == nil {
+		p = new(metadataProject)
+		metadataProjects.m[host] = p
+	}
+	metadataProjects.mu.Unlock()
+	p.once.Do(func() { p.id = queryMetadata(host, "project/project-id") })
+	return p.id
+}
+
+// queryMetadata returns the value of a metadata entry, or an empty string on
+// failure.
+func queryMetadata(host, path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Metadata-Flavor") != "Google" {
+		return ""
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,133 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+)
+
+// PubSubKey is the key of the group attribute that [PubSubMiddleware] adds to
+// all records logged for a Pub/Sub push request.
+const PubSubKey = "pubsub"
+
+// PubSubMiddleware returns a derived version of the given HTTP handler that
+// recognizes [Pub/Sub push requests].  For such requests, it adds a group
+// with the key [PubSubKey] to all records logged with the request context
+// (see [ContextWithAttrs]), containing the message ID, publish time,
+// subscription, and delivery attempt if available.  If the message has a
+// “googclient_traceparent” attribute, as set by Pub/Sub clients with
+// OpenTelemetry tracing enabled, the middleware associates the records with
+// that trace instead of the trace of the push request, so that they are
+// correlated with the publisher.  PubSubMiddleware supports both wrapped
+// messages, whose body is a JSON envelope, and unwrapped messages that carry
+// the metadata in headers.  Other requests pass through unchanged.  The
+// handler still sees the complete request body.
+//
+// Install PubSubMiddleware below [Middleware]:
+//
+//	http.Handle("/push", aelog.Middleware(aelog.PubSubMiddleware(handler)))
+//
+// [Pub/Sub push requests]: https://cloud.google.com/pubsub/docs/push
+func PubSubMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m, ok := pubSubMessage(r); ok {
+			ctx := ContextWithAttrs(r.Context(), m.attr())
+			if t, ok := parseTraceparent(m.Message.Attributes["googclient_traceparent"]); ok {
+				ctx = ContextWithTrace(ctx, t)
+			}
+			r = r.WithContext(ctx)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// pubSubEnvelope is the body of a wrapped push request.  See
+// https://cloud.google.com/pubsub/docs/push#receive_push.
+type pubSubEnvelope struct {
+	Message struct {
+		Attributes  map[string]string `json:"attributes"`
+		MessageID   string            `json:"messageId"`
+		PublishTime string            `json:"publishTime"`
+		OrderingKey string            `json:"orderingKey"`
+	} `json:"message"`
+	Subscription    string `json:"subscription"`
+	DeliveryAttempt int    `json:"deliveryAttempt"`
+}
+
+func (e *pubSubEnvelope) attr() slog.Attr {
+	attrs := []slog.Attr{slog.String("messageId", e.Message.MessageID)}
+	if t := e.Message.PublishTime; t != "" {
+		attrs = append(attrs, slog.String("publishTime", t))
+	}
+	if s := e.Subscription; s != "" {
+		attrs = append(attrs, slog.String("subscription", s))
+	}
+	if k := e.Message.OrderingKey; k != "" {
+		attrs = append(attrs, slog.String("orderingKey", k))
+	}
+	if n := e.DeliveryAttempt; n > 0 {
+		attrs = append(attrs, slog.Int("deliveryAttempt", n))
+	}
+	return slog.Attr{Key: PubSubKey, Value: slog.GroupValue(attrs...)}
+}
+
+// maxPubSubBody is the maximum size of a push request body that
+// pubSubMessage reads.  Messages can be up to 10 MB, and the envelope
+// encodes them in base64.
+const maxPubSubBody = 16 << 20
+
+// pubSubMessage returns the Pub/Sub message of a push request.  It returns
+// false if the request isn’t a push request.  It leaves the request body
+// intact.
+func pubSubMessage(r *http.Request) (*pubSubEnvelope, bool) {
+	if r.Method != http.MethodPost {
+		return nil, false
+	}
+	// Unwrapped messages with metadata, see
+	// https://cloud.google.com/pubsub/docs/payload-unwrapping.
+	if id := r.Header.Get("X-Goog-Pubsub-Message-Id"); id != "" {
+		e := new(pubSubEnvelope)
+		e.Message.MessageID = id
+		e.Message.PublishTime = r.Header.Get("X-Goog-Pubsub-Publish-Time")
+		e.Message.OrderingKey = r.Header.Get("X-Goog-Pubsub-Ordering-Key")
+		e.Subscription = r.Header.Get("X-Goog-Pubsub-Subscription-Name")
+		if tp := r.Header.Get("Googclient_traceparent"); tp != "" {
+			e.Message.Attributes = map[string]string{"googclient_traceparent": tp}
+		}
+		return e, true
+	}
+	if t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); t != "application/json" || r.Body == nil {
+		return nil, false
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxPubSubBody))
+	// Restore the body, including anything that we haven’t read.
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), r.Body), r.Body}
+	if err != nil || len(b) == maxPubSubBody {
+		return nil, false
+	}
+	e := new(pubSubEnvelope)
+	if json.Unmarshal(b, e) != nil || e.Message.MessageID == "" || e.Subscription == "" {
+		return nil, false
+	}
+	return e, true
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestPubSubMiddleware(t *testing.T) {
+	const body = `{
+  "message": {
+    "attributes": {"googclient_traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+    "data": "SGVsbG8=",
+    "messageId": "2070443601311540",
+    "publishTime": "2021-02-26T19:13:55.749Z"
+  },
+  "subscription": "projects/myproject/subscriptions/mysubscription",
+  "deliveryAttempt": 3
+}`
+	for _, tc := range []struct {
+		name   string
+		method string
+		header http.Header
+		body   string
+		want   map[string]any
+	}{
+		{
+			name:   "wrapped",
+			method: http.MethodPost,
+			header: http.Header{"Content-Type": {"application/json"}},
+			body:   body,
+			want: map[string]any{
+				"severity":                             "INFO",
+				"message":                              "info",
+				"logging.googleapis.com/trace":         "projects/test/traces/0af7651916cd43dd8448eb211c80319c",
+				"logging.googleapis.com/spanId":        "b7ad6b7169203331",
+				"logging.googleapis.com/trace_sampled": true,
+				"pubsub": map[string]any{
+					"messageId":       "2070443601311540",
+					"publishTime":     "2021-02-26T19:13:55.749Z",
+					"subscription":    "projects/myproject/subscriptions/mysubscription",
+					"deliveryAttempt": 3.0,
+				},
+			},
+		},
+		{
+			name:   "unwrapped",
+			method: http.MethodPost,
+			header: http.Header{
+				"Content-Type":                    {"text/plain"},
+				"X-Goog-Pubsub-Message-Id":        {"123"},
+				"X-Goog-Pubsub-Publish-Time":      {"2021-02-26T19:13:55.749Z"},
+				"X-Goog-Pubsub-Subscription-Name": {"projects/myproject/subscriptions/mysubscription"},
+			},
+			body: "Hello",
+			want: map[string]any{
+				"severity": "INFO",
+				"message":  "info",
+				"pubsub": map[string]any{
+					"messageId":    "123",
+					"publishTime":  "2021-02-26T19:13:55.749Z",
+					"subscription": "projects/myproject/subscriptions/mysubscription",
+				},
+			},
+		},
+		{
+			name:   "other JSON",
+			method: http.MethodPost,
+			header: http.Header{"Content-Type": {"application/json"}},
+			body:   `{"message": "hi"}`,
+			want:   map[string]any{"severity": "INFO", "message": "info"},
+		},
+		{
+			name:   "GET",
+			method: http.MethodGet,
+			header: http.Header{"Content-Type": {"application/json"}},
+			body:   body,
+			want:   map[string]any{"severity": "INFO", "message": "info"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{ProjectID: "test"}))
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				if got := string(b); got != tc.body {
+					t.Errorf("body = %q, want %q", got, tc.body)
+				}
+				log.InfoContext(r.Context(), "info")
+			}
+			req := httptest.NewRequest(tc.method, "/push", strings.NewReader(tc.body))
+			req.Header = tc.header
+			aelog.PubSubMiddleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+			got := parseRecords(t, buf)
+			want := []map[string]any{tc.want}
+			if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+				t.Error("-got +want", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import "strings"
+
+// redacted is the value that replaces redacted attributes, see
+// Options.RedactKeys.
+const redacted = "[REDACTED]"
+
+// redact returns whether the attribute with the given key in the currently
+// open groups should be redacted.
+func (s *encodeState) redact(key string) bool {
+	if len(s.enc.redactKeys) == 0 || isBuiltin(s.groups, key) {
+		return false
+	}
+	switch {
+	case len(s.groups) == 0 && key == MessageKey,
+		len(s.groups) == 1 && s.groups[0] == SourceLocationKey:
+		// Don’t redact built-in fields.
+		return false
+	}
+	for _, k := range s.enc.redactKeys {
+		if strings.EqualFold(k, key) || matchPath(k, s.groups, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath returns whether path is the case-insensitive dot-separated
+// concatenation of groups and key.
+func matchPath(path string, groups []string, key string) bool {
+	if len(groups) == 0 {
+		return false
+	}
+	for _, g := range groups {
+		if len(path) <= len(g) || path[len(g)] != '.' || !strings.EqualFold(path[:len(g)], g) {
+			return false
+		}
+		path = path[len(g)+1:]
+	}
+	return strings.EqualFold(path, key)
+}
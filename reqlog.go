@@ -0,0 +1,74 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// RequestLogger returns the request-scoped logger for the incoming request
+// that the given context belongs to.  The logger is only available if the
+// request was handled by a [Middleware] with [MiddlewareOptions.RequestLogger]
+// set; otherwise RequestLogger returns [slog.Default].
+func RequestLogger(ctx context.Context) *slog.Logger {
+	if i := infoFromContext(ctx); i != nil && i.logger != nil {
+		return i.logger
+	}
+	return slog.Default()
+}
+
+// boundHandler is an [slog.Handler] that associates records logged with a
+// context that doesn’t belong to an incoming request, for example the
+// background context used by [slog.Logger.Info], with a fixed request.  It
+// copies the request information and trace of that request onto the
+// context, so that other values such as attributes from [ContextWithAttrs]
+// are preserved.
+type boundHandler struct {
+	h   slog.Handler
+	ctx context.Context
+}
+
+func (h *boundHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.h.Enabled(h.context(ctx), l)
+}
+
+func (h *boundHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.h.Handle(h.context(ctx), r)
+}
+
+func (h *boundHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &boundHandler{h.h.WithAttrs(attrs), h.ctx}
+}
+
+func (h *boundHandler) WithGroup(name string) slog.Handler {
+	return &boundHandler{h.h.WithGroup(name), h.ctx}
+}
+
+func (h *boundHandler) context(ctx context.Context) context.Context {
+	if ctx == nil {
+		return h.ctx
+	}
+	if infoFromContext(ctx) != nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, httpInfoKey, h.ctx.Value(httpInfoKey))
+	if _, ok := TraceFromContext(ctx); !ok {
+		if t, ok := TraceFromContext(h.ctx); ok {
+			ctx = ContextWithTrace(ctx, t)
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// responseWriter wraps an http.ResponseWriter to capture the response status,
+// size, and the time when the response started.  See
+// MiddlewareOptions.CaptureResponse.
+type responseWriter struct {
+	http.ResponseWriter
+
+	// Handlers might write from other goroutines, and log records might
+	// read the fields at any time.
+	mu     sync.Mutex
+	status int       // zero if the response hasn’t started yet
+	size   int64     // number of body bytes written
+	start  time.Time // when status was set; zero if not yet
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	// Informational headers (1xx) can be written several times before
+	// the final header.
+	if w.status == 0 && code >= 200 {
+		w.status = code
+		w.start = time.Now()
+	}
+	w.mu.Unlock()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	if w.status == 0 {
+		// See the documentation of http.ResponseWriter.Write.
+		w.status = http.StatusOK
+		w.start = time.Now()
+	}
+	w.mu.Unlock()
+	n, err := w.ResponseWriter.Write(b)
+	w.mu.Lock()
+	w.size += int64(n)
+	w.mu.Unlock()
+	return n, err
+}
+
+// statusCode returns the response status.  If the handler hasn’t written
+// anything, the HTTP server will respond with 200 OK.
+func (w *responseWriter) statusCode() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// started returns the time when the handler started the response by writing
+// the final header or the first body bytes, or the zero time if it hasn’t
+// done so yet.
+func (w *responseWriter) started() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.start
+}
+
+// Unwrap returns the wrapped response writer so that
+// [http.ResponseController] can access its optional methods, for example
+// SetWriteDeadline and EnableFullDuplex.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush implements [http.Flusher].  It does nothing if the wrapped response
+// writer doesn’t support flushing.
+func (w *responseWriter) Flush() {
+	w.mu.Lock()
+	if w.status == 0 {
+		// Flushing sends the header.
+		w.status = http.StatusOK
+		w.start = time.Now()
+	}
+	w.mu.Unlock()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Push implements [http.Pusher].  It returns [http.ErrNotSupported] if the
+// wrapped response writer doesn’t support server push.
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Hijack implements [http.Hijacker], for example for WebSocket upgrades.  It
+// returns an error wrapping [http.ErrNotSupported] if the wrapped response
+// writer doesn’t support hijacking.  If the handler hasn’t started the
+// response yet, the response status becomes 101 Switching Protocols, since
+// that is what protocol upgrades respond with.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.mu.Lock()
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+		w.start = time.Now()
+	}
+	w.mu.Unlock()
+	return conn, rw, nil
+}
+
+// response returns the response status and the number of body bytes written
+// so far.  It returns false if the handler hasn’t started the response yet.
+func (w *responseWriter) response() (status int, size int64, started bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status, w.size, w.status != 0
+}
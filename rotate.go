@@ -0,0 +1,221 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenRotatingFile opens a [RotatingFile] that writes to the file with the
+// given name, creating it if necessary and appending to it otherwise.
+// Passing nil options has the same effect as passing a pointer to a zero
+// struct.
+func OpenRotatingFile(name string, opts *RotateOptions) (*RotatingFile, error) {
+	if opts == nil {
+		opts = new(RotateOptions)
+	}
+	f := &RotatingFile{name: name, opts: *opts}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// RotatingFile is an [io.Writer] that writes to a file and rotates it based
+// on size and age.  Rotating renames the file by appending a dot and a
+// timestamp to its name, and then starts a new file under the original name.
+// This is useful on Compute Engine and on-premises deployments where a
+// logging agent tails log files instead of capturing standard error.
+// RotatingFile only rotates between writes, so records stay intact.  It’s
+// safe for concurrent use.
+//
+// Use [OpenRotatingFile] to create RotatingFile objects.
+type RotatingFile struct {
+	name string
+	opts RotateOptions
+
+	mu     sync.Mutex
+	f      *os.File // nil if reopening failed or after Close
+	size   int64
+	opened time.Time
+	closed bool
+}
+
+// RotateOptions contains options for a [RotatingFile].
+type RotateOptions struct {
+	// Rotate before a write would make the file larger than this many
+	// bytes.  If zero, don’t rotate based on size.
+	MaxSize int64
+
+	// Rotate once the current file is older than this.  Empty files are
+	// never rotated.  If zero, don’t rotate based on age.
+	Interval time.Duration
+
+	// Delete the oldest rotated files when there are more than this many
+	// of them.  If zero, keep all of them.
+	MaxBackups int
+
+	// Delete rotated files that are older than this.  If zero, keep all
+	// of them.
+	MaxAge time.Duration
+
+	// If not nil, OnError is called with errors from rotations that
+	// Write starts, for example if deleting old files fails.  Write still
+	// writes the data to the current file in that case.  If nil, such
+	// errors are ignored.  OnError must not write to the RotatingFile.
+	OnError func(error)
+}
+
+// backupTimeFormat is the format of the timestamps in the names of rotated
+// files.  It sorts chronologically.
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// Write implements [io.Writer.Write].
+func (f *RotatingFile) Write(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.f == nil {
+		// Reopening the file after a previous rotation failed.
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	bySize := f.opts.MaxSize > 0 && f.size > 0 && f.size+int64(len(b)) > f.opts.MaxSize
+	byAge := f.opts.Interval > 0 && f.size > 0 && time.Since(f.opened) >= f.opts.Interval
+	if bySize || byAge {
+		if err := f.rotate(); err != nil {
+			if f.opts.OnError != nil {
+				f.opts.OnError(err)
+			}
+			if f.f == nil {
+				return 0, err
+			}
+		}
+	}
+	n, err := f.f.Write(b)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file immediately.  If renaming the file fails, Rotate
+// keeps writing to the original file.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Close closes the current file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
+
+// open opens the file.  f.mu must be locked or not yet shared.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.f, f.size, f.opened = file, fi.Size(), time.Now()
+	return nil
+}
+
+// rotate implements Rotate.  Afterwards, f.f is only nil if the file couldn’t
+// be reopened.  f.mu must be locked.
+func (f *RotatingFile) rotate() error {
+	var closeErr error
+	if f.f != nil {
+		closeErr = f.f.Close()
+		f.f = nil
+	}
+	backup := f.name + "." + time.Now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(f.name, backup); err != nil {
+		// Continue with the original file, for example if the
+		// error is temporary.
+		return errors.Join(closeErr, err, f.open())
+	}
+	if err := f.open(); err != nil {
+		return errors.Join(closeErr, err)
+	}
+	return errors.Join(closeErr, f.prune())
+}
+
+// prune deletes rotated files according to MaxBackups and MaxAge.  f.mu must be
+// locked.
+func (f *RotatingFile) prune() error {
+	if f.opts.MaxBackups <= 0 && f.opts.MaxAge <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(filepath.Dir(f.name))
+	if err != nil {
+		return err
+	}
+	prefix := filepath.Base(f.name) + "."
+	type backup struct {
+		name string
+		time time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		suffix, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, suffix)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{filepath.Join(filepath.Dir(f.name), e.Name()), t})
+	}
+	// Newest first.
+	slices.SortFunc(backups, func(a, b backup) int { return b.time.Compare(a.time) })
+	var errs []error
+	for i, b := range backups {
+		tooMany := f.opts.MaxBackups > 0 && i >= f.opts.MaxBackups
+		tooOld := f.opts.MaxAge > 0 && time.Since(b.time) > f.opts.MaxAge
+		if tooMany || tooOld {
+			errs = append(errs, os.Remove(b.name))
+		}
+	}
+	return errors.Join(errs...)
+}
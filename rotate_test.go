@@ -0,0 +1,157 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phst/aelog"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	f, err := aelog.OpenRotatingFile(name, &aelog.RotateOptions{MaxSize: 200, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := aelog.NewHandler(f, nil, nil)
+	log := slog.New(h)
+
+	const n = 10
+	for i := range n {
+		log.Info("message", "i", i, "padding", strings.Repeat("x", 50))
+	}
+	if err := h.Close(); err != nil {
+		t.Error(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each record is larger than half of MaxSize, so each file contains
+	// exactly one record.
+	if got := len(entries); got != 3 {
+		t.Errorf("got %d files, want three (the current file and two backups)", got)
+	}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) > 200 {
+			t.Errorf("file %s has %d bytes, more than the maximum", e.Name(), len(b))
+		}
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"i":9`) {
+		t.Errorf("current file doesn’t contain the last record: %s", b)
+	}
+}
+
+func TestRotatingFile_interval(t *testing.T) {
+	dir := t.TempDir()
+	f, err := aelog.OpenRotatingFile(filepath.Join(dir, "app.log"), &aelog.RotateOptions{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for range 3 {
+		time.Sleep(2 * time.Millisecond)
+		if _, err := f.Write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Empty files aren’t rotated.
+	if got := len(entries); got != 3 {
+		t.Errorf("got %d files, want three", got)
+	}
+}
+
+func TestRotatingFile_renameError(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	f, err := aelog.OpenRotatingFile(name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Renaming fails if the file has disappeared.
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Rotate(); err == nil {
+		t.Error("Rotate succeeded even though the file is missing")
+	}
+	if _, err := f.Write([]byte("after\n")); err != nil {
+		t.Errorf("Write after failed rotation: %v", err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "after\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRotateOptions_OnError(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	// A backup that can’t be deleted because it’s a nonempty directory.
+	old := name + ".20000102T030405.000000000Z"
+	if err := os.MkdirAll(filepath.Join(old, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	f, err := aelog.OpenRotatingFile(name, &aelog.RotateOptions{
+		MaxSize:    10,
+		MaxBackups: 1,
+		OnError:    func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, s := range []string{"first\n", "second\n"} {
+		if n, err := f.Write([]byte(s)); n != len(s) || err != nil {
+			t.Errorf("Write(%q) = %d, %v; want %d, nil", s, n, err, len(s))
+		}
+	}
+	if len(errs) != 1 {
+		t.Errorf("got errors %v, want one", errs)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "second\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
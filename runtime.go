@@ -0,0 +1,158 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"runtime/metrics"
+	"time"
+)
+
+// ReportRuntime periodically logs a snapshot of runtime statistics (heap
+// usage, garbage collection, goroutines, CPU time) to the given logger until
+// the context is canceled.  It logs a first snapshot immediately.  It reads the
+// statistics from [runtime/metrics], which doesn’t stop the world; the total
+// garbage collection pause time is an estimate.  Passing a nil logger uses
+// [slog.Default]; passing nil options has the same effect as passing a pointer
+// to a zero struct.  ReportRuntime blocks, so you typically want to call it in
+// a separate goroutine:
+//
+//	go aelog.ReportRuntime(ctx, nil, nil)
+func ReportRuntime(ctx context.Context, logger *slog.Logger, opts *RuntimeOptions) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if opts == nil {
+		opts = new(RuntimeOptions)
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	level := LevelInfo
+	if opts.Level != nil {
+		level = opts.Level.Level()
+	}
+	scope := opts.Scope
+	if scope == "" {
+		scope = "runtime"
+	}
+	report := func() {
+		logger.LogAttrs(ctx, level, "runtime statistics", slog.Attr{Key: scope, Value: runtimeSnapshot()})
+	}
+	report()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			report()
+		}
+	}
+}
+
+// RuntimeOptions contains options for [ReportRuntime].
+type RuntimeOptions struct {
+	// Interval between two snapshots.  If zero, ReportRuntime uses one
+	// minute.
+	Interval time.Duration
+
+	// Level of the log records.  If nil, ReportRuntime uses [LevelInfo].
+	Level slog.Leveler
+
+	// Name of the group containing the statistics.  This makes it easy to
+	// filter for the records in the Logs Explorer.  If empty,
+	// ReportRuntime uses “runtime”.
+	Scope string
+}
+
+// runtimeMetrics maps the names of the runtime metrics that runtimeSnapshot
+// reports to attribute keys.  Unlike runtime.ReadMemStats, reading them
+// doesn’t stop the world.
+var runtimeMetrics = []struct{ name, key string }{
+	{"/memory/classes/heap/objects:bytes", "heapAlloc"},
+	{"/gc/heap/objects:objects", "heapObjects"},
+	{"/gc/cycles/total:gc-cycles", "gcCycles"},
+	{"/sched/goroutines:goroutines", "goroutines"},
+	{"/sched/gomaxprocs:threads", "gomaxprocs"},
+	{"/cpu/classes/total:cpu-seconds", "cpuSeconds"},
+}
+
+// heapSysMetrics are the runtime metrics that add up to the heap memory
+// obtained from the operating system, like runtime.MemStats.HeapSys.
+var heapSysMetrics = []string{
+	"/memory/classes/heap/objects:bytes",
+	"/memory/classes/heap/unused:bytes",
+	"/memory/classes/heap/free:bytes",
+	"/memory/classes/heap/released:bytes",
+}
+
+// gcPausesMetric is the histogram of stop-the-world pauses for garbage
+// collection.
+const gcPausesMetric = "/sched/pauses/total/gc:seconds"
+
+func runtimeSnapshot() slog.Value {
+	samples := make([]metrics.Sample, 0, len(runtimeMetrics)+len(heapSysMetrics)+1)
+	for _, m := range runtimeMetrics {
+		samples = append(samples, metrics.Sample{Name: m.name})
+	}
+	for _, name := range heapSysMetrics {
+		samples = append(samples, metrics.Sample{Name: name})
+	}
+	samples = append(samples, metrics.Sample{Name: gcPausesMetric})
+	metrics.Read(samples)
+
+	var attrs []slog.Attr
+	for i, m := range runtimeMetrics {
+		switch v := samples[i].Value; v.Kind() {
+		case metrics.KindUint64:
+			attrs = append(attrs, slog.Uint64(m.key, v.Uint64()))
+		case metrics.KindFloat64:
+			attrs = append(attrs, slog.Float64(m.key, v.Float64()))
+		}
+	}
+	var heapSys uint64
+	for _, s := range samples[len(runtimeMetrics) : len(runtimeMetrics)+len(heapSysMetrics)] {
+		if s.Value.Kind() == metrics.KindUint64 {
+			heapSys += s.Value.Uint64()
+		}
+	}
+	attrs = append(attrs, slog.Uint64("heapSys", heapSys))
+	if v := samples[len(samples)-1].Value; v.Kind() == metrics.KindFloat64Histogram {
+		attrs = append(attrs, slog.Duration("gcPauseTotal", histogramSum(v.Float64Histogram())))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// histogramSum estimates the sum of the samples in a histogram of seconds,
+// using the midpoints of the buckets.
+func histogramSum(h *metrics.Float64Histogram) time.Duration {
+	var sum float64
+	for i, n := range h.Counts {
+		lo, hi := h.Buckets[i], h.Buckets[i+1]
+		switch {
+		case math.IsInf(lo, -1):
+			lo = hi
+		case math.IsInf(hi, 1):
+			hi = lo
+		}
+		sum += float64(n) * (lo + hi) / 2
+	}
+	return time.Duration(sum * float64(time.Second))
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/phst/aelog"
+)
+
+func TestReportRuntime(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, nil))
+
+	aelog.ReportRuntime(ctx, log, &aelog.RuntimeOptions{
+		Interval: 10 * time.Millisecond,
+		Level:    aelog.LevelNotice,
+		Scope:    "stats",
+	})
+
+	recs := parseRecords(t, buf)
+	if len(recs) == 0 {
+		t.Fatal("no records")
+	}
+	for _, rec := range recs {
+		if got, want := rec[aelog.SeverityKey], "NOTICE"; got != want {
+			t.Errorf("severity: got %q, want %q", got, want)
+		}
+		stats, ok := rec["stats"].(map[string]any)
+		if !ok {
+			t.Fatalf("record %v has no statistics", rec)
+		}
+		for _, key := range []string{"heapAlloc", "heapSys", "heapObjects", "gcCycles", "gcPauseTotal", "goroutines", "gomaxprocs", "cpuSeconds"} {
+			if _, ok := stats[key]; !ok {
+				t.Errorf("statistics %v lack key %q", stats, key)
+			}
+		}
+	}
+}
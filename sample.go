@@ -0,0 +1,303 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// NewSamplingHandler creates a new [SamplingHandler] that passes a sample of
+// records on to the given handler.  Passing nil options has the same effect
+// as passing a pointer to a zero struct.
+func NewSamplingHandler(h slog.Handler, opts *SamplingOptions) *SamplingHandler {
+	if opts == nil {
+		opts = new(SamplingOptions)
+	}
+	threshold := opts.Threshold
+	if threshold == nil {
+		threshold = LevelWarn
+	}
+	extractor := opts.TraceExtractor
+	if ah, ok := h.(*Handler); ok && extractor == nil {
+		extractor = ah.traceExtractor
+	}
+	r := &SamplingHandler{h: h, rate: opts.Rate, threshold: threshold, traceExtractor: extractor, metrics: opts.Metrics}
+	if len(opts.Rules) > 0 {
+		r.counter = &countingSampler{
+			rules:  make(map[string]SamplingRule, len(opts.Rules)),
+			start:  time.Now(),
+			counts: make(map[string]int),
+		}
+		for l, rule := range opts.Rules {
+			r.counter.rules[severityForLevel(l)] = rule
+		}
+	}
+	if opts.Budget > 0 {
+		interval := opts.ReportInterval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		now := time.Now()
+		r.adaptive = &adaptiveSampler{
+			h:          h,
+			budget:     opts.Budget,
+			interval:   interval,
+			start:      now,
+			lastReport: now,
+			counts:     make(map[string]int),
+			rates:      make(map[string]float64),
+		}
+	}
+	return r
+}
+
+// SamplingHandler is an [slog.Handler] that only passes a sample of records on
+// to another handler, to keep the cost of high-volume logging in check.
+// Records at or above a threshold level are always passed on.  Records logged
+// with the context of a request that belongs to a sampled trace (see
+// [Middleware] and [SamplingOptions.TraceExtractor]) are always passed on as
+// well, so that distributed traces never have missing log segments; sampling
+// only applies to unsampled traffic.
+//
+// Use [NewSamplingHandler] to create SamplingHandler objects; the zero
+// SamplingHandler isn’t valid.
+type SamplingHandler struct {
+	h              slog.Handler
+	rate           float64
+	threshold      slog.Leveler
+	traceExtractor func(context.Context) (Trace, bool)
+
+	// Shared between all derived handlers; nil if not adaptive.
+	adaptive *adaptiveSampler
+
+	// Shared between all derived handlers; nil if there are no rules.
+	counter *countingSampler
+
+	// Metrics; nil if not collected.
+	metrics *Metrics
+}
+
+// SamplingOptions contains options for a [SamplingHandler].
+type SamplingOptions struct {
+	// Probability in the interval [0, 1] with which the handler keeps a
+	// record that isn’t always kept.  If zero, the handler drops all such
+	// records.
+	Rate float64
+
+	// Records at or above this level are always kept.  If nil, the
+	// handler uses [LevelWarn].
+	Threshold slog.Leveler
+
+	// If positive, the handler ignores Rate and instead adapts the
+	// sampling rates dynamically, targeting this many records per
+	// second.  The handler measures the number of records per severity
+	// each second and assigns the budget from the highest severity
+	// downwards, so that lower severities are sampled first.  Records
+	// that are always kept count towards the budget as well.
+	Budget float64
+
+	// If Budget is positive, the handler logs the effective sampling rate
+	// for each severity at this interval.  It logs these meta-records
+	// lazily when handling a record, at [LevelInfo], bypassing sampling,
+	// but not associated with the trace or HTTP request of that record.
+	// If zero, the handler uses one minute.
+	ReportInterval time.Duration
+
+	// Deterministic sampling rules per severity.  The key of each rule
+	// is a level, and the rule applies to all records with the same
+	// severity as that level, for example all DEBUG records for the key
+	// [LevelDebug].  Rules take precedence over Rate and Budget.
+	Rules map[slog.Level]SamplingRule
+
+	// If not nil, the handler counts the records that it doesn’t pass on
+	// in Metrics.
+	Metrics *Metrics
+
+	// If not nil, the handler calls TraceExtractor to determine whether a
+	// record belongs to a sampled trace, like [Options.TraceExtractor].
+	// If nil and the handler wraps a [Handler], it uses the
+	// TraceExtractor of that Handler.
+	TraceExtractor func(ctx context.Context) (Trace, bool)
+}
+
+// SamplingRule is a deterministic sampling rule for a [SamplingHandler].  Each
+// second, the handler keeps the first Initial records of a severity, and
+// after that every Thereafter-th record.  For example, Initial = 100 and
+// Thereafter = 10 keeps up to 100 records per second, and one in ten beyond
+// that.
+type SamplingRule struct {
+	// Number of records to keep each second before sampling starts.
+	Initial int
+
+	// Keep every Thereafter-th record once Initial records have been
+	// kept in the current second.  If zero, drop all of them.
+	Thereafter int
+}
+
+// Enabled implements [slog.Handler.Enabled].
+func (h *SamplingHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.h.Enabled(ctx, l)
+}
+
+// Handle implements [slog.Handler.Handle].
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.keep(ctx, r.Level) {
+		h.metrics.sample()
+		return nil
+	}
+	return h.h.Handle(ctx, r)
+}
+
+// WithAttrs implements [slog.Handler.WithAttrs].
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	r := *h
+	r.h = h.h.WithAttrs(attrs)
+	return &r
+}
+
+// WithGroup implements [slog.Handler.WithGroup].
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	r := *h
+	r.h = h.h.WithGroup(name)
+	return &r
+}
+
+func (h *SamplingHandler) keep(ctx context.Context, l slog.Level) bool {
+	always := l >= h.threshold.Level() || h.traceSampled(ctx)
+	if h.counter != nil && !always {
+		if keep, ok := h.counter.keep(l); ok {
+			return keep
+		}
+	}
+	rate := h.rate
+	if h.adaptive != nil {
+		rate = h.adaptive.rate(l)
+	}
+	return always || rand.Float64() < rate
+}
+
+// traceSampled returns whether the trace of a record is sampled.
+func (h *SamplingHandler) traceSampled(ctx context.Context) bool {
+	if h.traceExtractor != nil {
+		if t, ok := h.traceExtractor(ctx); ok && t.ID != "" {
+			return t.Sampled
+		}
+	}
+	return traceSampled(ctx)
+}
+
+// countingSampler implements SamplingOptions.Rules.
+type countingSampler struct {
+	rules map[string]SamplingRule // severity → rule
+
+	mu     sync.Mutex
+	start  time.Time      // start of the current second
+	counts map[string]int // severity → records in the current second
+}
+
+// keep counts a record at the given level and returns whether to keep it.  It
+// returns false as second value if there’s no rule for the level.
+func (s *countingSampler) keep(l slog.Level) (keep, ok bool) {
+	sev := severityForLevel(l)
+	rule, ok := s.rules[sev]
+	if !ok {
+		return false, false
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.start) >= time.Second {
+		s.start = now
+		clear(s.counts)
+	}
+	s.counts[sev]++
+	n := s.counts[sev] - rule.Initial
+	return n <= 0 || rule.Thereafter > 0 && n%rule.Thereafter == 0, true
+}
+
+// adaptiveSampler adjusts sampling rates per severity to stay within a
+// budget.
+type adaptiveSampler struct {
+	h        slog.Handler // for meta-records
+	budget   float64      // records per second
+	interval time.Duration
+
+	mu         sync.Mutex
+	start      time.Time          // start of the current measurement window
+	lastReport time.Time          // last meta-record
+	counts     map[string]int     // severity → records in the current window
+	rates      map[string]float64 // severity → rate; missing means 1
+}
+
+// rate counts a record at the given level and returns the sampling rate for
+// it.
+func (s *adaptiveSampler) rate(l slog.Level) float64 {
+	sev := severityForLevel(l)
+	now := time.Now()
+	var report []slog.Attr
+	s.mu.Lock()
+	if now.Sub(s.start) >= time.Second {
+		s.adjust(now)
+	}
+	s.counts[sev]++
+	rate, ok := s.rates[sev]
+	if !ok {
+		rate = 1
+	}
+	if now.Sub(s.lastReport) >= s.interval {
+		s.lastReport = now
+		for _, sev := range severities {
+			if r, ok := s.rates[sev]; ok {
+				report = append(report, slog.Float64(sev, r))
+			}
+		}
+	}
+	s.mu.Unlock()
+	// The meta-record doesn’t belong to the request that triggered it, so
+	// don’t associate it with that request’s trace or HTTP request.
+	ctx := context.Background()
+	if report != nil && s.h.Enabled(ctx, LevelInfo) {
+		r := slog.NewRecord(now, LevelInfo, "adaptive sampling rates", 0)
+		r.AddAttrs(slog.Attr{Key: "samplingRates", Value: slog.GroupValue(report...)})
+		// Like slog.Logger, ignore errors from the handler.
+		_ = s.h.Handle(ctx, r)
+	}
+	return rate
+}
+
+// adjust recomputes the rates from the counts of the window that ends now.
+// s.mu must be locked.
+func (s *adaptiveSampler) adjust(now time.Time) {
+	elapsed := now.Sub(s.start).Seconds()
+	remaining := s.budget
+	for i := len(severities) - 1; i >= 0; i-- {
+		sev := severities[i]
+		perSecond := float64(s.counts[sev]) / elapsed
+		if perSecond == 0 {
+			// Start unsampled if a severity shows up again.
+			delete(s.rates, sev)
+			continue
+		}
+		rate := min(1, remaining/perSecond)
+		s.rates[sev] = rate
+		remaining = max(0, remaining-perSecond*rate)
+	}
+	clear(s.counts)
+	s.start = now
+}
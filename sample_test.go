@@ -0,0 +1,204 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewSamplingHandler(aelog.NewHandler(buf, nil, nil), nil))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		log.InfoContext(r.Context(), "info", "trace", r.Header.Get("X-Cloud-Trace-Context"))
+		log.WarnContext(r.Context(), "warning", "trace", r.Header.Get("X-Cloud-Trace-Context"))
+	}
+	for _, trace := range []string{"sampled/1;o=1", "unsampled/1;o=0", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if trace != "" {
+			req.Header.Set("X-Cloud-Trace-Context", trace)
+		}
+		aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{"severity": "INFO", "message": "info", "trace": "sampled/1;o=1"},
+		{"severity": "WARNING", "message": "warning", "trace": "sampled/1;o=1"},
+		{"severity": "WARNING", "message": "warning", "trace": "unsampled/1;o=0"},
+		{"severity": "WARNING", "message": "warning", "trace": ""},
+	}
+	if diff := cmp.Diff(got, want, ignoreFields(aelog.TimeKey, "httpRequest")); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestSamplingOptions_TraceExtractor(t *testing.T) {
+	type key struct{}
+	extractor := func(ctx context.Context) (aelog.Trace, bool) {
+		t, ok := ctx.Value(key{}).(aelog.Trace)
+		return t, ok
+	}
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewSamplingHandler(aelog.NewHandler(buf, nil, &aelog.Options{ProjectID: "test", TraceExtractor: extractor}), nil))
+
+	log.InfoContext(context.WithValue(context.Background(), key{}, aelog.Trace{ID: "sampled", Sampled: true}), "sampled")
+	log.InfoContext(context.WithValue(context.Background(), key{}, aelog.Trace{ID: "unsampled"}), "unsampled")
+	log.InfoContext(context.Background(), "none")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{
+			"severity":                             "INFO",
+			"message":                              "sampled",
+			"logging.googleapis.com/trace":         "projects/test/traces/sampled",
+			"logging.googleapis.com/trace_sampled": true,
+		},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestSamplingHandler_rate(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewSamplingHandler(aelog.NewHandler(buf, nil, nil), &aelog.SamplingOptions{Rate: 0.5}))
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		log.Info("info")
+	}
+
+	// The probability that this fails is negligible.
+	if got := len(parseRecords(t, buf)); got < n/4 || got > 3*n/4 {
+		t.Errorf("got %d records, want about %d", got, n/2)
+	}
+}
+
+func TestSamplingHandler_adaptive(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewSamplingHandler(aelog.NewHandler(buf, &slog.HandlerOptions{Level: aelog.LevelDebug}, nil), &aelog.SamplingOptions{
+		Budget:         10,
+		ReportInterval: time.Millisecond,
+	}))
+
+	// Measurement windows are one second long.  In the first window,
+	// everything is kept.
+	const n = 1000
+	for i := 0; i < n; i++ {
+		log.Debug("debug")
+	}
+	time.Sleep(1100 * time.Millisecond)
+	for i := 0; i < n; i++ {
+		log.Debug("debug")
+	}
+	log.Error("error")
+
+	var debug, errors int
+	var rates map[string]any
+	for _, rec := range parseRecords(t, buf) {
+		switch rec[aelog.SeverityKey] {
+		case "DEBUG":
+			debug++
+		case "ERROR":
+			errors++
+		case "INFO":
+			if r, ok := rec["samplingRates"].(map[string]any); ok {
+				rates = r
+			}
+		}
+	}
+	// The probability that this fails is negligible.
+	if debug < n || debug > n+n/10 {
+		t.Errorf("got %d debug records, want about %d", debug, n+10)
+	}
+	if errors != 1 {
+		t.Errorf("got %d error records, want one", errors)
+	}
+	if r, ok := rates["DEBUG"].(float64); !ok || r <= 0 || r >= 0.1 {
+		t.Errorf("invalid sampling rates %v", rates)
+	}
+}
+
+func TestSamplingHandler_adaptiveReport(t *testing.T) {
+	opts := &aelog.SamplingOptions{Budget: 10, ReportInterval: time.Millisecond}
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewSamplingHandler(aelog.NewHandler(buf, nil, &aelog.Options{ProjectID: "test"}), opts))
+	// The meta-records are at INFO level, so this handler never sees them.
+	warnBuf := new(bytes.Buffer)
+	warnLog := slog.New(aelog.NewSamplingHandler(aelog.NewHandler(warnBuf, &slog.HandlerOptions{Level: aelog.LevelWarn}, nil), opts))
+
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "abc", SpanID: "123", Sampled: true})
+	log.WarnContext(ctx, "warning")
+	warnLog.WarnContext(ctx, "warning")
+	// Rates are only known after the first measurement window.
+	time.Sleep(1100 * time.Millisecond)
+	log.WarnContext(ctx, "warning")
+	warnLog.WarnContext(ctx, "warning")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{"severity": "WARNING", "message": "warning", "logging.googleapis.com/trace": "projects/test/traces/abc", "logging.googleapis.com/spanId": "123", "logging.googleapis.com/trace_sampled": true},
+		{"severity": "INFO", "message": "adaptive sampling rates", "samplingRates": map[string]any{"WARNING": 1.0}},
+		{"severity": "WARNING", "message": "warning", "logging.googleapis.com/trace": "projects/test/traces/abc", "logging.googleapis.com/spanId": "123", "logging.googleapis.com/trace_sampled": true},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	for _, rec := range parseRecords(t, warnBuf) {
+		if rec[aelog.SeverityKey] != "WARNING" {
+			t.Errorf("unexpected record %v", rec)
+		}
+	}
+}
+
+func TestSamplingHandler_rules(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewSamplingHandler(aelog.NewHandler(buf, &slog.HandlerOptions{Level: aelog.LevelDebug}, nil), &aelog.SamplingOptions{
+		Rate: 1,
+		Rules: map[slog.Level]aelog.SamplingRule{
+			aelog.LevelInfo:  {Initial: 10, Thereafter: 100},
+			aelog.LevelDebug: {Initial: 5},
+		},
+	}))
+
+	// Assume that this loop finishes within a second.
+	const n = 1000
+	for i := 0; i < n; i++ {
+		log.Debug("debug")
+		log.Info("info")
+		log.Log(context.Background(), aelog.LevelNotice, "notice")
+	}
+
+	counts := make(map[string]int)
+	for _, rec := range parseRecords(t, buf) {
+		counts[rec[aelog.SeverityKey].(string)]++
+	}
+	want := map[string]int{"DEBUG": 5, "INFO": 10 + (n-10)/100, "NOTICE": n}
+	if diff := cmp.Diff(counts, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ServerErrorLog returns a logger for [http.Server.ErrorLog] that sends the
+// messages of the HTTP server to h as structured records.  It recognizes
+// common messages and assigns them appropriate levels:
+//
+//   - Panics in handlers (“http: panic serving …”) at [LevelCritical], with
+//     the panic value as message and the stack trace in [StackTraceKey] so
+//     that Error Reporting picks them up.
+//   - TLS handshake errors at [LevelInfo], since they are typically caused
+//     by clients such as port scanners.
+//   - Superfluous WriteHeader calls and similar handler mistakes at
+//     [LevelWarn].
+//   - All other messages at [LevelError].
+//
+// Where possible, records contain the address of the client in an attribute
+// “remoteAddr”.
+func ServerErrorLog(h slog.Handler) *log.Logger {
+	return log.New(serverErrorWriter{h}, "", 0)
+}
+
+type serverErrorWriter struct{ h slog.Handler }
+
+// Write implements io.Writer.  log.Logger calls Write once per message.
+func (w serverErrorWriter) Write(b []byte) (int, error) {
+	ctx := context.Background()
+	level, msg, attrs := parseServerError(strings.TrimSuffix(string(b), "\n"))
+	if !w.h.Enabled(ctx, level) {
+		return len(b), nil
+	}
+	r := slog.NewRecord(time.Now(), level, msg, 0)
+	r.AddAttrs(attrs...)
+	return len(b), w.h.Handle(ctx, r)
+}
+
+// parseServerError parses a message written by net/http.
+func parseServerError(s string) (slog.Level, string, []slog.Attr) {
+	if rest, ok := strings.CutPrefix(s, "http: panic serving "); ok {
+		// The format is “http: panic serving ADDR: VALUE\nSTACK”.
+		first, stack, _ := strings.Cut(rest, "\n")
+		addr, value, _ := strings.Cut(first, ": ")
+		return LevelCritical, "panic: " + value, []slog.Attr{
+			slog.String("remoteAddr", addr),
+			slog.String(StackTraceKey, "panic: "+value+"\n\n"+stack),
+		}
+	}
+	if rest, ok := strings.CutPrefix(s, "http: TLS handshake error from "); ok {
+		// The format is “http: TLS handshake error from ADDR: ERROR”.
+		addr, _, _ := strings.Cut(rest, ": ")
+		return LevelInfo, s, []slog.Attr{slog.String("remoteAddr", addr)}
+	}
+	for _, p := range []string{"http: superfluous response.WriteHeader call", "http: URL query contains semicolon"} {
+		if strings.HasPrefix(s, p) {
+			return LevelWarn, s, nil
+		}
+	}
+	return LevelError, s, nil
+}
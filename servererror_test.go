@@ -0,0 +1,76 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestServerErrorLog(t *testing.T) {
+	buf := new(syncBuffer)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	mux.HandleFunc("/header", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.ErrorLog = aelog.ServerErrorLog(aelog.NewHandler(buf, nil, nil))
+	srv.Start()
+	for _, p := range []string{"/panic", "/header"} {
+		resp, err := http.Get(srv.URL + p)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+	srv.Close()
+	srv.Config.ErrorLog.Print("http: TLS handshake error from 192.0.2.1:1234: EOF")
+	srv.Config.ErrorLog.Print("http: Accept error: broken; retrying in 5ms")
+
+	got := parseRecords(t, bytes.NewReader(buf.Bytes()))
+	if len(got) != 4 {
+		t.Fatalf("got %d records, want four: %v", len(got), got)
+	}
+	stack, ok := got[0][aelog.StackTraceKey].(string)
+	if !ok || !strings.HasPrefix(stack, "panic: boom\n\ngoroutine ") {
+		t.Errorf("panic record has invalid stack trace %q", stack)
+	}
+	if addr, ok := got[0]["remoteAddr"].(string); !ok || !strings.HasPrefix(addr, "127.0.0.1:") {
+		t.Errorf("panic record has invalid remote address %q", addr)
+	}
+	for _, r := range got {
+		delete(r, aelog.StackTraceKey)
+	}
+	want := []map[string]any{
+		{"severity": "CRITICAL", "message": "panic: boom", "remoteAddr": got[0]["remoteAddr"]},
+		{"severity": "WARNING", "message": got[1]["message"]},
+		{"severity": "INFO", "message": "http: TLS handshake error from 192.0.2.1:1234: EOF", "remoteAddr": "192.0.2.1:1234"},
+		{"severity": "ERROR", "message": "http: Accept error: broken; retrying in 5ms"},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	if msg, _ := got[1]["message"].(string); !strings.HasPrefix(msg, "http: superfluous response.WriteHeader call") {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
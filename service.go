@@ -0,0 +1,81 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"log/slog"
+	"os"
+)
+
+// ServiceContextKey is the key of the group that describes the service
+// that wrote a log entry.  See [Options.ServiceContext].
+const ServiceContextKey = "serviceContext"
+
+// ServiceContext identifies the service that writes log entries.  [Error
+// Reporting] uses it to group errors, and it’s also useful for filtering log
+// entries.
+//
+// [Error Reporting]: https://cloud.google.com/error-reporting/docs/formatting-error-messages
+type ServiceContext struct {
+	// Name of the service, for example the App Engine service or the
+	// Cloud Run service.
+	Service string
+
+	// Version of the service, for example the App Engine version or the
+	// Cloud Run revision.  May be empty.
+	Version string
+}
+
+// attrs returns the members of the serviceContext group.  It returns nil if
+// the service is empty.
+func (c ServiceContext) attrs() []slog.Attr {
+	if c.Service == "" {
+		return nil
+	}
+	attrs := []slog.Attr{slog.String("service", c.Service)}
+	if c.Version != "" {
+		attrs = append(attrs, slog.String("version", c.Version))
+	}
+	return attrs
+}
+
+// serviceEnvVars lists the pairs of environment variables that
+// detectServiceContext consults, in order of precedence.
+var serviceEnvVars = []struct{ service, version string }{
+	// https://cloud.google.com/appengine/docs/standard/go/runtime#environment_variables
+	{"GAE_SERVICE", "GAE_VERSION"},
+	// https://cloud.google.com/run/docs/container-contract#env-vars
+	{"K_SERVICE", "K_REVISION"},
+}
+
+// detectServiceContext attempts to auto-detect the current service from the
+// environment.  It returns a zero ServiceContext if that fails.
+func detectServiceContext() ServiceContext {
+	for _, v := range serviceEnvVars {
+		if s := os.Getenv(v.service); s != "" {
+			return ServiceContext{s, os.Getenv(v.version)}
+		}
+	}
+	return ServiceContext{}
+}
+
+// serviceContextAttrs returns the members of the given attribute and true if
+// it is a top-level serviceContext group.  Otherwise, it returns false.
+func serviceContextAttrs(a slog.Attr, topLevel bool) ([]slog.Attr, bool) {
+	if topLevel && a.Key == ServiceContextKey && a.Value.Kind() == slog.KindGroup {
+		return a.Value.Group(), true
+	}
+	return nil, false
+}
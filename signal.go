@@ -0,0 +1,66 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+)
+
+// ToggleLevelOnSignal starts a goroutine that toggles the given level
+// variable between the levels a and b whenever the process receives one of
+// the given signals, until ctx is done.  This allows operators to turn on
+// debug logging for a running process that has no admin endpoints, for
+// example using “kill -USR1 <pid>”.  A signal sets the variable to b if it
+// contains a, and to a otherwise.  If no signals are given,
+// ToggleLevelOnSignal uses SIGUSR1 on Unix systems and does nothing on other
+// systems.  Each toggle logs a record at [LevelNotice] using [slog.Default],
+// with the new level in the attribute “newLevel”.
+//
+// Use [Handler.LevelVar] to obtain the level variable of a handler:
+//
+//	h := aelog.NewHandler(os.Stderr, nil, nil)
+//	aelog.ToggleLevelOnSignal(ctx, h.LevelVar(), aelog.LevelInfo, aelog.LevelDebug)
+func ToggleLevelOnSignal(ctx context.Context, v *slog.LevelVar, a, b slog.Level, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = defaultToggleSignals
+	}
+	if len(sigs) == 0 {
+		// signal.Notify would relay all signals.
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				l := a
+				if v.Level() == a {
+					l = b
+				}
+				// Don’t use the key “level”, since a Handler
+				// would treat it as the severity of the record.
+				slog.Default().LogAttrs(ctx, LevelNotice, "changing log level", slog.String("newLevel", severityForLevel(l)))
+				v.Set(l)
+			}
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestToggleLevelOnSignal(t *testing.T) {
+	buf := new(syncBuffer)
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(aelog.NewHandler(buf, nil, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	v := new(slog.LevelVar)
+	aelog.ToggleLevelOnSignal(ctx, v, aelog.LevelInfo, aelog.LevelDebug, syscall.SIGUSR2)
+
+	for _, want := range []slog.Level{aelog.LevelDebug, aelog.LevelInfo} {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for v.Level() != want && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if got := v.Level(); got != want {
+			t.Errorf("level: got %v, want %v", got, want)
+		}
+	}
+	cancel()
+
+	got := parseRecords(t, bytes.NewReader(buf.Bytes()))
+	want := []map[string]any{
+		{"severity": "NOTICE", "message": "changing log level", "newLevel": "DEBUG"},
+		{"severity": "NOTICE", "message": "changing log level", "newLevel": "INFO"},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
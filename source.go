@@ -0,0 +1,67 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// ModuleRoot returns the directory of the main module at build time, with a
+// trailing slash, for use as [Options.TrimSourcePrefix].  It must be called
+// from a package in the main module, typically the main package.
+// ModuleRoot returns an empty string if it can’t determine the directory,
+// for example because the program was built with -trimpath, in which case
+// source file names are already relative.
+func ModuleRoot() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok || bi.Main.Path == "" {
+		return ""
+	}
+	pc, file, _, ok := runtime.Caller(1)
+	if !ok || !path.IsAbs(file) {
+		return ""
+	}
+	pkg := funcPackage(runtime.FuncForPC(pc).Name())
+	if pkg == "main" {
+		pkg = bi.Path
+	}
+	// External test packages have a package path ending in “_test”, but
+	// live in the directory of the package under test.
+	pkg = strings.TrimSuffix(pkg, "_test")
+	// rel is the package directory relative to the module root, with a
+	// leading slash unless it’s empty.
+	rel, ok := strings.CutPrefix(pkg, bi.Main.Path)
+	if !ok || rel != "" && !strings.HasPrefix(rel, "/") {
+		return ""
+	}
+	dir, ok := strings.CutSuffix(path.Dir(file), rel)
+	if !ok {
+		return ""
+	}
+	return dir + "/"
+}
+
+// funcPackage returns the package path of the function with the given
+// fully-qualified name, as returned by [runtime.Func.Name].
+func funcPackage(name string) string {
+	slash := strings.LastIndexByte(name, '/') + 1
+	if i := strings.IndexByte(name[slash:], '.'); i >= 0 {
+		return name[:slash+i]
+	}
+	return name
+}
@@ -0,0 +1,60 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/phst/aelog"
+)
+
+func TestModuleRoot(t *testing.T) {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("no caller information")
+	}
+	want := filepath.ToSlash(filepath.Dir(file)) + "/"
+	if got := aelog.ModuleRoot(); got != want {
+		t.Errorf("ModuleRoot() = %q, want %q", got, want)
+	}
+}
+
+func TestOptions_TrimSourcePrefix(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(
+		buf,
+		&slog.HandlerOptions{AddSource: true},
+		&aelog.Options{TrimSourcePrefix: aelog.ModuleRoot(), ErrorReporting: true},
+	))
+	log.Error("error")
+
+	got := parseRecords(t, buf)
+	if len(got) != 1 {
+		t.Fatalf("got %d records, want one", len(got))
+	}
+	loc, _ := got[0]["logging.googleapis.com/sourceLocation"].(map[string]any)
+	if file := loc["file"]; file != "source_test.go" {
+		t.Errorf("source file: got %q, want %q", file, "source_test.go")
+	}
+	ctx, _ := got[0]["context"].(map[string]any)
+	report, _ := ctx["reportLocation"].(map[string]any)
+	if file := report["filePath"]; file != "source_test.go" {
+		t.Errorf("report location: got %q, want %q", file, "source_test.go")
+	}
+}
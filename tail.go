@@ -0,0 +1,77 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// tailBuffer implements MiddlewareOptions.TailBuffer.  It holds back records
+// below LevelWarn for a request until it’s clear whether the request failed.
+type tailBuffer struct {
+	mu      sync.Mutex
+	closed  bool
+	records []tailRecord
+}
+
+// tailRecord is a record held back by a tailBuffer, together with the output
+// it would have been written to.
+type tailRecord struct {
+	out output
+	e   entry
+}
+
+// add adds a record to the buffer.  It returns false if the caller should
+// write the record normally, either because the buffer has already been
+// finished or because the record is at LevelWarn or above.  In the latter
+// case, add first writes all held-back records and finishes the buffer.
+func (b *tailBuffer) add(ctx context.Context, out output, e entry) bool {
+	if e.Level >= LevelWarn {
+		b.finish(ctx, true)
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+	// The attributes come from a pooled slice.
+	e.attrs = slices.Clone(e.attrs)
+	b.records = append(b.records, tailRecord{out, e})
+	return true
+}
+
+// finish writes the held-back records if flush is true, and drops them
+// otherwise.  Records added afterwards are written normally.  Only the first
+// call has an effect.
+func (b *tailBuffer) finish(ctx context.Context, flush bool) {
+	b.mu.Lock()
+	records := b.records
+	b.closed = true
+	b.records = nil
+	b.mu.Unlock()
+	if !flush {
+		for _, t := range records {
+			t.out.metrics.drop(1)
+		}
+		return
+	}
+	for _, t := range records {
+		// Like slog.Logger, ignore errors from the handler.
+		_ = t.out.handle(t.e)
+	}
+}
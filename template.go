@@ -0,0 +1,89 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+)
+
+// ParamsKey is the key of the attribute that contains the parameters of a
+// message template.  See [Logf].
+const ParamsKey = "params"
+
+// Logf logs a message template.  Unlike [fmt.Sprintf], Logf doesn’t
+// substitute the arguments into the template.  Instead, the template itself
+// becomes the log message, and the arguments are logged as a list in an
+// attribute with key [ParamsKey].  This makes it easy to group log entries by
+// template, for example:
+//
+//	aelog.Logf(ctx, log, slog.LevelInfo, "user %s purchased %d items", user, n)
+//
+// results in a record like
+//
+//	{"severity":"INFO","message":"user %s purchased %d items","params":["alice",3]}
+//
+// Errors are logged using their Error method; all other arguments are
+// marshaled as JSON.  A nil logger means [slog.Default].
+//
+// There’s no Errorf counterpart to [Warnf], since it would be easily confused
+// with [fmt.Errorf]; use Logf with [LevelError] instead.
+func Logf(ctx context.Context, l *slog.Logger, level slog.Level, format string, args ...any) {
+	logf(ctx, l, level, format, args)
+}
+
+// Debugf calls [Logf] with the default logger and [LevelDebug].
+func Debugf(ctx context.Context, format string, args ...any) {
+	logf(ctx, nil, LevelDebug, format, args)
+}
+
+// Infof calls [Logf] with the default logger and [LevelInfo].
+func Infof(ctx context.Context, format string, args ...any) {
+	logf(ctx, nil, LevelInfo, format, args)
+}
+
+// Noticef calls [Logf] with the default logger and [LevelNotice].
+func Noticef(ctx context.Context, format string, args ...any) {
+	logf(ctx, nil, LevelNotice, format, args)
+}
+
+// Warnf calls [Logf] with the default logger and [LevelWarn].
+func Warnf(ctx context.Context, format string, args ...any) {
+	logf(ctx, nil, LevelWarn, format, args)
+}
+
+func logf(ctx context.Context, l *slog.Logger, level slog.Level, format string, args []any) {
+	var attrs []any
+	if len(args) > 0 {
+		params := make([]any, len(args))
+		for i, a := range args {
+			if err, ok := a.(error); ok {
+				// Errors typically don’t marshal to anything
+				// useful.  Like the fmt package, don’t call
+				// the Error method of a nil pointer.
+				if v := reflect.ValueOf(err); v.Kind() == reflect.Pointer && v.IsNil() {
+					a = "<nil>"
+				} else {
+					a = err.Error()
+				}
+			}
+			params[i] = a
+		}
+		attrs = []any{slog.Any(ParamsKey, params)}
+	}
+	// Skip logf and the exported wrapper function.
+	logAt(ctx, l, level, 2, format, attrs...)
+}
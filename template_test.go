@@ -0,0 +1,96 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func ExampleLogf() {
+	// Suppress timestamp noise.
+	removeTime := func(_ []string, a slog.Attr) slog.Attr {
+		if a.Key == aelog.TimeKey {
+			return slog.Group("")
+		}
+		return a
+	}
+	log := slog.New(aelog.NewHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime}, nil))
+
+	aelog.Logf(context.Background(), log, aelog.LevelInfo, "user %s purchased %d items", "alice", 3)
+	// Output:
+	// {"severity":"INFO","message":"user %s purchased %d items","params":["alice",3]}
+}
+
+func TestInfof(t *testing.T) {
+	buf := new(bytes.Buffer)
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{AddSource: true}, nil)))
+
+	aelog.Infof(context.Background(), "no params")
+	aelog.Warnf(context.Background(), "failed: %v", errors.New("boom"))
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{
+			"severity": "INFO",
+			"message":  "no params",
+			"logging.googleapis.com/sourceLocation": map[string]any{
+				"function": "github.com/phst/aelog_test.TestInfof",
+			},
+		},
+		{
+			"severity": "WARNING",
+			"message":  "failed: %v",
+			"params":   []any{"boom"},
+			"logging.googleapis.com/sourceLocation": map[string]any{
+				"function": "github.com/phst/aelog_test.TestInfof",
+			},
+		},
+	}
+	if diff := cmp.Diff(got, want, ignoreFields(aelog.TimeKey, "file", "line")); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+type pointerError struct{ msg string }
+
+func (e *pointerError) Error() string { return e.msg }
+
+func TestLogf_nilError(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, nil))
+
+	var err *pointerError
+	aelog.Logf(context.Background(), log, aelog.LevelError, "failed: %v", err)
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity": "ERROR",
+		"message":  "failed: %v",
+		"params":   []any{"<nil>"},
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// ContextWithTenant returns a derived context that carries the given tenant.
+// A [TenantHandler] routes records logged with such a context to the handler
+// for that tenant.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// TenantFromContext returns the tenant stored in the context by
+// [ContextWithTenant], or an empty string if there’s none.
+func TenantFromContext(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey).(string)
+	return t
+}
+
+// NewTenantHandler creates a new [TenantHandler].  For each tenant, the
+// TenantHandler calls newHandler once to create the handler for that tenant;
+// the handler can for example write to a tenant-specific writer.  The
+// TenantHandler and all handlers derived from it share these handlers, and
+// they are never released.  Records that don’t belong to any tenant go to the
+// handler for the empty tenant.  Passing nil options has the same effect as
+// passing a pointer to a zero struct.
+func NewTenantHandler(newHandler func(tenant string) slog.Handler, opts *TenantOptions) *TenantHandler {
+	if opts == nil {
+		opts = new(TenantOptions)
+	}
+	return &TenantHandler{
+		key: opts.Key,
+		bases: &tenantBases{
+			newHandler: newHandler,
+			max:        opts.MaxTenants,
+			handlers:   make(map[string]slog.Handler),
+		},
+		children: new(sync.Map),
+	}
+}
+
+// TenantHandler is an [slog.Handler] that routes records to per-tenant
+// handlers.  This keeps logs of different tenants isolated without having to
+// create a separate logger per tenant at every call site.  The tenant of a
+// record is the string value of an attribute with key [TenantOptions.Key],
+// either passed to the logging function or added with [slog.Logger.With].  If
+// there’s no such attribute, the tenant is taken from the context (see
+// [ContextWithTenant]).
+//
+// Use [NewTenantHandler] to create TenantHandler objects; the zero
+// TenantHandler isn’t valid.
+type TenantHandler struct {
+	key string
+
+	// Handlers returned by newHandler, shared with derived handlers.
+	bases *tenantBases
+
+	// Tenant from an attribute added by WithAttrs.
+	tenant    string
+	hasTenant bool
+
+	// Attributes and groups added by WithAttrs and WithGroup, from
+	// outermost to innermost.  We apply them to the child handlers.
+	goas []groupOrAttrs
+
+	// Maps tenant names to child handlers with goas already applied.
+	// Each derived handler has its own map, which is empty if goas is.
+	children *sync.Map
+}
+
+// tenantBases creates and stores the handlers for each tenant.
+type tenantBases struct {
+	newHandler func(string) slog.Handler
+	max        int
+
+	mu       sync.Mutex
+	handlers map[string]slog.Handler // guarded by mu
+	tenants  int                     // nonempty keys of handlers, guarded by mu
+}
+
+// TenantOptions contains options for a [TenantHandler].
+type TenantOptions struct {
+	// Key of the attribute containing the tenant.  If empty, the
+	// handler only uses the context to determine the tenant.
+	Key string
+
+	// If positive, the maximum number of nonempty tenants that get their
+	// own handler.  Records of further tenants go to the handler for the
+	// empty tenant.  Without a limit, the number of handlers is unbounded,
+	// so set one if tenant names come from untrusted input.
+	MaxTenants int
+}
+
+// Enabled implements [slog.Handler.Enabled].  Because Enabled doesn’t have
+// access to the record attributes, it only uses the tenant from
+// [slog.Logger.With] or the context.
+func (h *TenantHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.child(h.tenantFor(ctx, nil)).Enabled(ctx, l)
+}
+
+// Handle implements [slog.Handler.Handle].
+func (h *TenantHandler) Handle(ctx context.Context, r slog.Record) error {
+	c := h.child(h.tenantFor(ctx, &r))
+	if !c.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return c.Handle(ctx, r)
+}
+
+// WithAttrs implements [slog.Handler.WithAttrs].
+func (h *TenantHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	r := h.with(groupOrAttrs{attrs: slices.Clone(attrs)})
+	if t, ok := h.tenantAttr(attrs); ok {
+		r.tenant = t
+		r.hasTenant = true
+	}
+	return r
+}
+
+// WithGroup implements [slog.Handler.WithGroup].
+func (h *TenantHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *TenantHandler) with(goa groupOrAttrs) *TenantHandler {
+	r := *h
+	r.goas = append(slices.Clip(h.goas), goa)
+	r.children = new(sync.Map)
+	return &r
+}
+
+// tenantFor determines the tenant for a record.  r may be nil if the record
+// isn’t known yet.
+func (h *TenantHandler) tenantFor(ctx context.Context, r *slog.Record) string {
+	if r != nil && h.key != "" {
+		var t string
+		var found bool
+		r.Attrs(func(a slog.Attr) bool {
+			t, found = h.tenantAttr([]slog.Attr{a})
+			return !found
+		})
+		if found {
+			return t
+		}
+	}
+	if h.hasTenant {
+		return h.tenant
+	}
+	return TenantFromContext(ctx)
+}
+
+func (h *TenantHandler) tenantAttr(attrs []slog.Attr) (string, bool) {
+	if h.key == "" {
+		return "", false
+	}
+	for _, a := range attrs {
+		if a.Key == h.key {
+			return a.Value.Resolve().String(), true
+		}
+	}
+	return "", false
+}
+
+func (h *TenantHandler) child(tenant string) slog.Handler {
+	if len(h.goas) == 0 {
+		_, c := h.bases.get(tenant)
+		return c
+	}
+	if c, ok := h.children.Load(tenant); ok {
+		return c.(slog.Handler)
+	}
+	tenant, c := h.bases.get(tenant)
+	for _, goa := range h.goas {
+		if goa.group == "" {
+			c = c.WithAttrs(goa.attrs)
+		} else {
+			c = c.WithGroup(goa.group)
+		}
+	}
+	actual, _ := h.children.LoadOrStore(tenant, c)
+	return actual.(slog.Handler)
+}
+
+// get returns the handler for the given tenant, creating it if necessary.  It
+// also returns the tenant that the handler belongs to, which is empty if the
+// maximum number of tenants has been reached.
+func (b *tenantBases) get(tenant string) (string, slog.Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.handlers[tenant]; ok {
+		return tenant, c
+	}
+	if tenant != "" && b.max > 0 && b.tenants >= b.max {
+		tenant = ""
+		if c, ok := b.handlers[tenant]; ok {
+			return tenant, c
+		}
+	}
+	// Call newHandler with the lock held so that it’s called only once
+	// per tenant.
+	c := b.newHandler(tenant)
+	b.handlers[tenant] = c
+	if tenant != "" {
+		b.tenants++
+	}
+	return tenant, c
+}
+
+const tenantKey contextKey = 2
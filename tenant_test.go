@@ -0,0 +1,108 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestTenantHandler(t *testing.T) {
+	bufs := make(map[string]*bytes.Buffer)
+	calls := make(map[string]int)
+	newHandler := func(tenant string) slog.Handler {
+		calls[tenant]++
+		if bufs[tenant] == nil {
+			bufs[tenant] = new(bytes.Buffer)
+		}
+		return aelog.NewHandler(bufs[tenant], nil, nil)
+	}
+	log := slog.New(aelog.NewTenantHandler(newHandler, &aelog.TenantOptions{Key: "tenant"}))
+	ctx := context.Background()
+
+	log.Info("no tenant")
+	log.InfoContext(aelog.ContextWithTenant(ctx, "ctx"), "from context")
+	log.Info("from attr", "tenant", "attr")
+	log.With("tenant", "with").WithGroup("group").InfoContext(aelog.ContextWithTenant(ctx, "ctx"), "from With", "foo", "bar")
+	// Derived loggers, for example per request, reuse the tenant handlers.
+	for range 3 {
+		log.With("request", 1).DebugContext(aelog.ContextWithTenant(ctx, "ctx"), "hidden")
+	}
+
+	got := make(map[string][]map[string]any)
+	for tenant, buf := range bufs {
+		got[tenant] = parseRecords(t, buf)
+	}
+	want := map[string][]map[string]any{
+		"": {{
+			"severity": "INFO",
+			"message":  "no tenant",
+		}},
+		"ctx": {{
+			"severity": "INFO",
+			"message":  "from context",
+		}},
+		"attr": {{
+			"severity": "INFO",
+			"message":  "from attr",
+			"tenant":   "attr",
+		}},
+		"with": {{
+			"severity": "INFO",
+			"message":  "from With",
+			"tenant":   "with",
+			"group":    map[string]any{"foo": "bar"},
+		}},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+	wantCalls := map[string]int{"": 1, "ctx": 1, "attr": 1, "with": 1}
+	if diff := cmp.Diff(calls, wantCalls); diff != "" {
+		t.Error("newHandler calls: -got +want", diff)
+	}
+}
+
+func TestTenantOptions_MaxTenants(t *testing.T) {
+	var tenants []string
+	buf := new(bytes.Buffer)
+	newHandler := func(tenant string) slog.Handler {
+		tenants = append(tenants, tenant)
+		return aelog.NewHandler(buf, nil, nil).WithAttrs([]slog.Attr{slog.String("handler", tenant)})
+	}
+	log := slog.New(aelog.NewTenantHandler(newHandler, &aelog.TenantOptions{Key: "tenant", MaxTenants: 2}))
+	for _, tenant := range []string{"a", "b", "c", "a", "d"} {
+		log.With("x", 1).Info("info", "tenant", tenant)
+	}
+
+	// Enabled asks the handler for the empty tenant first, because it
+	// doesn’t know the record attributes yet.
+	if diff := cmp.Diff(tenants, []string{"", "a", "b"}); diff != "" {
+		t.Error("newHandler calls: -got +want", diff)
+	}
+	var handlers []any
+	for _, r := range parseRecords(t, buf) {
+		handlers = append(handlers, r["handler"])
+	}
+	if diff := cmp.Diff(handlers, []any{"a", "b", "", "a", ""}); diff != "" {
+		t.Error("handlers: -got +want", diff)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// TimeFormat is the serialization of time values, including the time of
+// records.  See [Options.TimeFormat].
+type TimeFormat int
+
+const (
+	// RFC 3339 string with nanosecond precision and without trailing
+	// zeros, for example “2025-01-02T03:04:05.123456789Z”.  This is the
+	// default.
+	TimeFormatRFC3339Nano TimeFormat = iota
+
+	// RFC 3339 string with exactly three fractional digits, for example
+	// “2025-01-02T03:04:05.120Z”.  Some parsers only accept millisecond
+	// precision or a fixed number of digits.
+	TimeFormatRFC3339Millis
+
+	// Integer number of nanoseconds since the Unix epoch, for example
+	// 1735787045123456789.  Cloud Logging doesn’t recognize numeric
+	// timestamps, so use this format only for other consumers.  Times
+	// that can’t be represented this way, that is, before 1678 or after
+	// 2262, use [TimeFormatRFC3339Nano] instead.
+	TimeFormatUnixNano
+)
+
+// Range of times that TimeFormatUnixNano can represent.
+var (
+	minUnixNano = time.Unix(0, math.MinInt64)
+	maxUnixNano = time.Unix(0, math.MaxInt64)
+)
+
+// layout returns the layout string for RFC 3339 formats.
+func (f TimeFormat) layout() string {
+	if f == TimeFormatRFC3339Millis {
+		return "2006-01-02T15:04:05.000Z07:00"
+	}
+	return time.RFC3339Nano
+}
+
+// appendTime appends the JSON encoding of t in format f to b.
+func (f TimeFormat) appendTime(b []byte, t time.Time) []byte {
+	if f == TimeFormatUnixNano && !t.Before(minUnixNano) && !t.After(maxUnixNano) {
+		return strconv.AppendInt(b, t.UnixNano(), 10)
+	}
+	b = append(b, '"')
+	b = t.AppendFormat(b, f.layout())
+	return append(b, '"')
+}
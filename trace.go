@@ -0,0 +1,173 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Trace identifies the Cloud Trace trace and span that a log record belongs
+// to.  See [ContextWithTrace] and [TraceFromContext].
+type Trace struct {
+	// Bare trace ID, typically 32 hexadecimal characters.  The handler
+	// converts it to a full resource name if it knows the project ID.
+	ID string
+
+	// Span ID within the trace.  May be empty.
+	SpanID string
+
+	// Whether SpanID is a decimal number as in the X-Cloud-Trace-Context
+	// header, rather than 16 hexadecimal digits as in the W3C traceparent
+	// header.  [TraceFromHeader] and [Middleware] set it for
+	// X-Cloud-Trace-Context headers.  If it’s false, [SetTraceHeader]
+	// treats span IDs with 16 hexadecimal digits as hexadecimal and other
+	// span IDs as decimal, which is ambiguous for 16 decimal digits.
+	DecimalSpanID bool
+
+	// Whether the caller has decided to sample the trace.
+	Sampled bool
+}
+
+// ContextWithTrace returns a derived context that associates log records with
+// the given trace.  [Middleware] calls ContextWithTrace for incoming requests
+// with a W3C traceparent or X-Cloud-Trace-Context header, preferring the
+// former if both are present; custom instrumentations for other
+// kinds of servers can use it in the same way so that [Handler] correlates
+// log records with the trace.  An empty trace ID removes any existing
+// association.
+func ContextWithTrace(ctx context.Context, t Trace) context.Context {
+	return context.WithValue(ctx, traceKey, t)
+}
+
+// TraceFromContext returns the trace associated with the given context by
+// [ContextWithTrace] or [Middleware].  It returns false if there’s no such
+// trace.
+func TraceFromContext(ctx context.Context) (Trace, bool) {
+	t, ok := ctx.Value(traceKey).(Trace)
+	return t, ok && t.ID != ""
+}
+
+// traceSampled returns whether the current request belongs to a trace that
+// the caller has decided to sample.
+func traceSampled(ctx context.Context) bool {
+	t, _ := TraceFromContext(ctx)
+	return t.Sampled
+}
+
+// TraceFromHeader returns the trace described by the W3C traceparent or
+// X-Cloud-Trace-Context header in the given request header, preferring the
+// former if both are present.  It returns false if neither header specifies a
+// trace.  Custom instrumentations can pass the result to [ContextWithTrace].
+func TraceFromHeader(h http.Header) (Trace, bool) {
+	t := requestTrace(h)
+	return t, t.ID != ""
+}
+
+// SetTraceHeader sets the W3C traceparent and X-Cloud-Trace-Context headers in
+// h to describe the trace associated with ctx (see [TraceFromContext]), so
+// that a downstream service that receives a request with these headers joins
+// the same trace.  Use it for outgoing requests; [Transport] can do this
+// automatically.  SetTraceHeader returns false and leaves h alone if there’s
+// no trace.  It only sets the traceparent header if the trace ID and span ID
+// can be represented in its format, that is, 32 and 16 hexadecimal digits;
+// span IDs from X-Cloud-Trace-Context headers are decimal numbers, which
+// SetTraceHeader converts as needed.
+func SetTraceHeader(ctx context.Context, h http.Header) bool {
+	t, ok := TraceFromContext(ctx)
+	if !ok {
+		return false
+	}
+	// The traceparent header uses a hexadecimal span ID, the
+	// X-Cloud-Trace-Context header a decimal one.
+	var spanHex string
+	var spanDec uint64
+	if n, err := strconv.ParseUint(t.SpanID, 16, 64); err == nil && len(t.SpanID) == 16 && !t.DecimalSpanID {
+		spanHex, spanDec = t.SpanID, n
+	} else if n, err := strconv.ParseUint(t.SpanID, 10, 64); err == nil && n != 0 {
+		spanHex, spanDec = fmt.Sprintf("%016x", n), n
+	}
+	xctc := t.ID
+	if spanDec != 0 {
+		xctc += "/" + strconv.FormatUint(spanDec, 10)
+	}
+	flags := "00"
+	if t.Sampled {
+		xctc += ";o=1"
+		flags = "01"
+	}
+	h.Set("X-Cloud-Trace-Context", xctc)
+	if isHex(t.ID, 32) && spanHex != "" {
+		h.Set("traceparent", fmt.Sprintf("00-%s-%s-%s", t.ID, spanHex, flags))
+	} else {
+		h.Del("traceparent")
+	}
+	return true
+}
+
+// requestTrace returns the trace of an incoming request.  It prefers the W3C
+// traceparent header over the X-Cloud-Trace-Context header.
+func requestTrace(h http.Header) Trace {
+	if t, ok := parseTraceparent(h.Get("traceparent")); ok {
+		return t
+	}
+	// https://cloud.google.com/trace/docs/setup#force-trace
+	s, opts, _ := strings.Cut(h.Get("X-Cloud-Trace-Context"), ";")
+	trace, span, _ := strings.Cut(s, "/")
+	return Trace{ID: trace, SpanID: span, DecimalSpanID: span != "", Sampled: opts == "o=1"}
+}
+
+// parseTraceparent parses a W3C traceparent header.  It returns false if the
+// header is missing or invalid.  See
+// https://www.w3.org/TR/trace-context/#traceparent-header.
+func parseTraceparent(s string) (Trace, bool) {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 {
+		return Trace{}, false
+	}
+	version, trace, span, flags := parts[0], parts[1], parts[2], parts[3]
+	// Future versions may append fields, but version 00 has exactly four.
+	// Version ff is invalid.
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return Trace{}, false
+	}
+	if !isHex(trace, 32) || !isHex(span, 16) || !isHex(flags, 2) {
+		return Trace{}, false
+	}
+	if trace == strings.Repeat("0", 32) || span == strings.Repeat("0", 16) {
+		return Trace{}, false
+	}
+	f, _ := hex.DecodeString(flags)
+	return Trace{ID: trace, SpanID: span, Sampled: f[0]&1 != 0}, true
+}
+
+// isHex returns whether s consists of exactly n lowercase hexadecimal digits.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+const traceKey contextKey = 4
@@ -0,0 +1,209 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestContextWithTrace(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{ProjectID: "test"}))
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "abc", SpanID: "123"})
+	log.InfoContext(ctx, "info")
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{{
+		"severity":                      "INFO",
+		"message":                       "info",
+		"logging.googleapis.com/trace":  "projects/test/traces/abc",
+		"logging.googleapis.com/spanId": "123",
+	}}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestTraceFromContext(t *testing.T) {
+	var gotTrace aelog.Trace
+	var gotOK, reqOK bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		gotTrace, gotOK = aelog.TraceFromContext(r.Context())
+		_, reqOK = aelog.HTTPRequestFromContext(r.Context())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "abc/123;o=1")
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	if !gotOK {
+		t.Fatal("TraceFromContext: no trace")
+	}
+	if diff := cmp.Diff(gotTrace, aelog.Trace{ID: "abc", SpanID: "123", DecimalSpanID: true, Sampled: true}); diff != "" {
+		t.Error("TraceFromContext: -got +want", diff)
+	}
+	if !reqOK {
+		t.Error("HTTPRequestFromContext: no request")
+	}
+	if _, ok := aelog.TraceFromContext(context.Background()); ok {
+		t.Error("TraceFromContext: unexpected trace in background context")
+	}
+}
+
+func TestMiddleware_traceparent(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		traceparent string
+		cloud       string
+		want        aelog.Trace
+	}{
+		{
+			"traceparent",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"",
+			aelog.Trace{ID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+		},
+		{
+			"preferred",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			"abc/123;o=1",
+			aelog.Trace{ID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+		},
+		{
+			"invalid",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"abc/123;o=1",
+			aelog.Trace{ID: "abc", SpanID: "123", DecimalSpanID: true, Sampled: true},
+		},
+		{
+			"future version",
+			"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03-extra",
+			"",
+			aelog.Trace{ID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+		},
+		{
+			"uppercase",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01",
+			"",
+			aelog.Trace{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got aelog.Trace
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				got, _ = aelog.TraceFromContext(r.Context())
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.traceparent != "" {
+				req.Header.Set("traceparent", tc.traceparent)
+			}
+			if tc.cloud != "" {
+				req.Header.Set("X-Cloud-Trace-Context", tc.cloud)
+			}
+			aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Error("-got +want", diff)
+			}
+		})
+	}
+}
+
+func TestSetTraceHeader(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		trace aelog.Trace
+		want  http.Header
+	}{
+		{
+			name:  "none",
+			trace: aelog.Trace{},
+			want:  http.Header{},
+		},
+		{
+			name:  "hex span",
+			trace: aelog.Trace{ID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331", Sampled: true},
+			want: http.Header{
+				"Traceparent":           {"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+				"X-Cloud-Trace-Context": {"0af7651916cd43dd8448eb211c80319c/13235353014750950193;o=1"},
+			},
+		},
+		{
+			name:  "decimal span",
+			trace: aelog.Trace{ID: "0af7651916cd43dd8448eb211c80319c", SpanID: "123"},
+			want: http.Header{
+				"Traceparent":           {"00-0af7651916cd43dd8448eb211c80319c-000000000000007b-00"},
+				"X-Cloud-Trace-Context": {"0af7651916cd43dd8448eb211c80319c/123"},
+			},
+		},
+		{
+			name:  "16 decimal digits",
+			trace: aelog.Trace{ID: "0af7651916cd43dd8448eb211c80319c", SpanID: "1234567890123456", DecimalSpanID: true},
+			want: http.Header{
+				"Traceparent":           {"00-0af7651916cd43dd8448eb211c80319c-000462d53c8abac0-00"},
+				"X-Cloud-Trace-Context": {"0af7651916cd43dd8448eb211c80319c/1234567890123456"},
+			},
+		},
+		{
+			name:  "short trace",
+			trace: aelog.Trace{ID: "abc", SpanID: "123"},
+			want:  http.Header{"X-Cloud-Trace-Context": {"abc/123"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := aelog.ContextWithTrace(context.Background(), tc.trace)
+			h := make(http.Header)
+			if got, want := aelog.SetTraceHeader(ctx, h), tc.trace.ID != ""; got != want {
+				t.Errorf("SetTraceHeader = %t, want %t", got, want)
+			}
+			if diff := cmp.Diff(h, tc.want); diff != "" {
+				t.Error("-got +want", diff)
+			}
+			// Round trip.
+			if got, ok := aelog.TraceFromHeader(h); ok != (tc.trace.ID != "") || got.ID != tc.trace.ID || got.Sampled != tc.trace.Sampled {
+				t.Errorf("TraceFromHeader = %+v, %t; want %+v", got, ok, tc.trace)
+			}
+		})
+	}
+}
+
+func TestSetTraceHeader_decimalSpan(t *testing.T) {
+	// A 16-digit decimal span ID from X-Cloud-Trace-Context must not be
+	// mistaken for a hexadecimal one.
+	const xctc = "0af7651916cd43dd8448eb211c80319c/1234567890123456;o=1"
+	var got http.Header
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		got = make(http.Header)
+		aelog.SetTraceHeader(r.Context(), got)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Cloud-Trace-Context", xctc)
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	want := http.Header{
+		"Traceparent":           {"00-0af7651916cd43dd8448eb211c80319c-000462d53c8abac0-01"},
+		"X-Cloud-Trace-Context": {xctc},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
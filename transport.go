@@ -0,0 +1,135 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net/http"
+	"time"
+)
+
+// Transport is an [http.RoundTripper] for outgoing HTTP requests that
+// cooperates with [Middleware].  Use it as the transport of an [http.Client]
+// and pass the request context (or a derived context) to outgoing requests,
+// for example using [http.NewRequestWithContext].  The zero Transport is a
+// valid transport that passes requests on to [http.DefaultTransport].
+type Transport struct {
+	// Transport for the actual requests.  If nil, use
+	// [http.DefaultTransport].
+	Base http.RoundTripper
+
+	// If set, log a record for each outgoing request, containing the
+	// method, target host and URL, response status, latency until the
+	// response headers arrived, and retry count (see
+	// [ContextWithRetryCount]).  The URL omits the query string, the
+	// fragment, and user information, since they often contain
+	// credentials.  If the request context is an incoming request context,
+	// the records are correlated with the incoming request.
+	LogRequests bool
+
+	// Level of the records for successful requests.  Requests that fail
+	// or result in a server error (status 5xx) are logged at [LevelWarn]
+	// or Level, whichever is higher.  If nil, use [LevelInfo].
+	Level slog.Leveler
+
+	// Logger for the records.  If nil, use [slog.Default].
+	Logger *slog.Logger
+
+	// If set, propagate the trace associated with the request context to
+	// the server using [SetTraceHeader], so that the server joins the
+	// trace.  Requests that already have a traceparent or
+	// X-Cloud-Trace-Context header are passed on unchanged.  Only enable
+	// this for requests to trusted servers, since the headers reveal the
+	// trace ID.
+	PropagateTrace bool
+}
+
+// RoundTrip implements [http.RoundTripper.RoundTrip].
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t.PropagateTrace && r.Header.Get("traceparent") == "" && r.Header.Get("X-Cloud-Trace-Context") == "" {
+		// RoundTrip must not modify the request.
+		h := make(http.Header)
+		if SetTraceHeader(r.Context(), h) {
+			r = r.Clone(r.Context())
+			if r.Header == nil {
+				r.Header = h
+			} else {
+				maps.Copy(r.Header, h)
+			}
+		}
+	}
+	if !t.LogRequests {
+		return base.RoundTrip(r)
+	}
+	start := time.Now()
+	resp, err := base.RoundTrip(r)
+	t.log(r, resp, err, time.Since(start))
+	return resp, err
+}
+
+func (t *Transport) log(r *http.Request, resp *http.Response, err error, latency time.Duration) {
+	level := LevelInfo
+	if t.Level != nil {
+		level = t.Level.Level()
+	}
+	attrs := []slog.Attr{
+		slog.String("requestMethod", r.Method),
+		slog.String("host", r.URL.Host),
+		slog.String("requestUrl", RefererNoQuery.scrub(r.URL.String())),
+	}
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	attrs = append(attrs, slog.String("latency", FormatDuration(latency)))
+	if n := retryCount(r.Context()); n > 0 {
+		attrs = append(attrs, slog.Int("retryCount", n))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if err != nil || resp.StatusCode >= 500 {
+		level = max(level, LevelWarn)
+	}
+	logger := t.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.LogAttrs(r.Context(), level, "outgoing request", slog.Attr{Key: OutgoingRequestKey, Value: slog.GroupValue(attrs...)})
+}
+
+// OutgoingRequestKey is the key of the group attribute describing an
+// outgoing request.  See [Transport.LogRequests].
+const OutgoingRequestKey = "outgoingRequest"
+
+// ContextWithRetryCount returns a derived context that records the number of
+// previous attempts for an outgoing request.  Code that retries outgoing
+// requests can use it so that [Transport] includes the retry count in its
+// records.
+func ContextWithRetryCount(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, retryCountKey, n)
+}
+
+func retryCount(ctx context.Context) int {
+	n, _ := ctx.Value(retryCountKey).(int)
+	return n
+}
+
+const retryCountKey contextKey = 3
@@ -0,0 +1,137 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestTransport(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer backend.Close()
+	u, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, nil, &aelog.Options{ProjectID: "test"}))
+	client := &http.Client{Transport: &aelog.Transport{
+		Base:        backend.Client().Transport,
+		LogRequests: true,
+		Logger:      log,
+	}}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		for i, path := range []string{"/ok?key=secret", "/fail"} {
+			ctx := aelog.ContextWithRetryCount(r.Context(), i)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL+path, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "abc/123;o=1")
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{
+			"severity":                             "INFO",
+			"message":                              "outgoing request",
+			"logging.googleapis.com/trace":         "projects/test/traces/abc",
+			"logging.googleapis.com/trace_sampled": true,
+			"outgoingRequest": map[string]any{
+				"requestMethod": "GET",
+				"host":          u.Host,
+				"requestUrl":    backend.URL + "/ok",
+				"status":        200.0,
+			},
+		},
+		{
+			"severity":                             "WARNING",
+			"message":                              "outgoing request",
+			"logging.googleapis.com/trace":         "projects/test/traces/abc",
+			"logging.googleapis.com/trace_sampled": true,
+			"outgoingRequest": map[string]any{
+				"requestMethod": "GET",
+				"host":          u.Host,
+				"requestUrl":    backend.URL + "/fail",
+				"status":        502.0,
+				"retryCount":    1.0,
+			},
+		},
+	}
+	if diff := cmp.Diff(got, want, ignoreFields(aelog.TimeKey, "httpRequest", "logging.googleapis.com/spanId", "latency")); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestTransport_PropagateTrace(t *testing.T) {
+	var got []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("traceparent"))
+	}))
+	defer backend.Close()
+	client := &http.Client{Transport: &aelog.Transport{Base: backend.Client().Transport, PropagateTrace: true}}
+
+	ctx := aelog.ContextWithTrace(context.Background(), aelog.Trace{ID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331", Sampled: true})
+	for _, explicit := range []string{"", "00-11111111111111111111111111111111-2222222222222222-00"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if explicit != "" {
+			req.Header.Set("traceparent", explicit)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if explicit == "" && len(req.Header) != 0 {
+			t.Errorf("Transport modified the request header: %v", req.Header)
+		}
+	}
+	want := []string{
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-11111111111111111111111111111111-2222222222222222-00",
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
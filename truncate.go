@@ -0,0 +1,57 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"log/slog"
+	"unicode/utf8"
+)
+
+// TruncatedKey is the key of the attribute that [Handler] adds to entries
+// that it had to truncate because of [Options.MaxEntryBytes].  Its value is
+// always true.
+const TruncatedKey = "truncated"
+
+// minTruncateLength is the shortest length to which appendTruncated
+// truncates strings.
+const minTruncateLength = 64
+
+// appendTruncated appends the JSON encoding of r to b, truncating it to at
+// most e.maxEntryBytes bytes if possible.  It first tries to truncate long
+// string values, with decreasing limits.  If that doesn’t work, it drops all
+// attributes.
+func (e *encoder) appendTruncated(b []byte, r entry) []byte {
+	start := len(b)
+	for limit := e.maxEntryBytes / 2; limit >= minTruncateLength; limit /= 2 {
+		b = e.appendLimited(b[:start], r, limit)
+		if len(b)-start <= e.maxEntryBytes {
+			return b
+		}
+	}
+	bare := entry{Record: slog.NewRecord(r.Time, r.Level, r.Message, r.PC), truncated: true}
+	return e.appendLimited(b[:start], bare, minTruncateLength)
+}
+
+// truncate returns a prefix of s that is at most n bytes long, without
+// splitting UTF-8 sequences.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,290 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Violation describes a way in which a log entry doesn’t conform to the
+// [structured logging] contract of Google Cloud Logging.  Such entries are
+// typically still ingested, but some of their fields are silently ignored or
+// misinterpreted.
+//
+// [structured logging]: https://cloud.google.com/logging/docs/structured-logging
+type Violation struct {
+	// Zero-based index of the offending entry.
+	Entry int
+
+	// Path of the offending field, separated by dots, e.g.
+	// “httpRequest.status”.  Empty if the entry as a whole is invalid.
+	Field string
+
+	// Human-readable description of the problem.
+	Message string
+}
+
+// String implements [fmt.Stringer].
+func (v Violation) String() string {
+	if v.Field == "" {
+		return fmt.Sprintf("entry %d: %s", v.Entry, v.Message)
+	}
+	return fmt.Sprintf("entry %d: field %s: %s", v.Entry, v.Field, v.Message)
+}
+
+// Validate reads log entries in JSON format, one entry per line, and checks
+// them against the structured logging contract: severities must be valid
+// [severity names], special fields such as httpRequest must have the right
+// types, and labels must be within the documented limits.  Validate is meant
+// for tests that want to ensure that log entries don’t silently degrade in
+// production.  It returns an error only if reading fails; invalid entries
+// result in violations, ordered by entry and by field name within each entry.
+//
+// [severity names]: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#logseverity
+func Validate(r io.Reader) ([]Violation, error) {
+	var vs []Violation
+	s := bufio.NewScanner(r)
+	// Cloud Logging entries can be up to 256 KiB, so the default
+	// buffer size isn’t enough.
+	s.Buffer(nil, 1<<20)
+	for i := 0; s.Scan(); i++ {
+		v := validator{entry: i}
+		v.validate(s.Bytes())
+		vs = append(vs, v.violations...)
+	}
+	return vs, s.Err()
+}
+
+type validator struct {
+	entry      int
+	violations []Violation
+}
+
+func (v *validator) fail(field, format string, args ...any) {
+	v.violations = append(v.violations, Violation{v.entry, field, fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(line []byte) {
+	var m map[string]any
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		v.fail("", "invalid JSON object: %s", err)
+		return
+	}
+	// Iterate in a fixed order so that violations are deterministic.
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		val := m[key]
+		switch key {
+		case SeverityKey:
+			if s, ok := val.(string); !ok || !validSeverities[s] {
+				v.fail(key, "invalid severity %v", val)
+			}
+		case MessageKey, "logging.googleapis.com/trace", "logging.googleapis.com/spanId", "logging.googleapis.com/insertId":
+			v.string(key, val)
+		case TimeKey:
+			v.time(key, val)
+		case "logging.googleapis.com/trace_sampled":
+			v.bool(key, val)
+		case "httpRequest":
+			v.object(key, val, httpRequestFields)
+		case SourceLocationKey:
+			v.object(key, val, sourceLocationFields)
+		case "logging.googleapis.com/operation":
+			v.object(key, val, operationFields)
+		case "logging.googleapis.com/labels":
+			v.labels(key, val)
+		}
+	}
+}
+
+func (v *validator) string(field string, val any) {
+	if _, ok := val.(string); !ok {
+		v.fail(field, "got %T, want string", val)
+	}
+}
+
+func (v *validator) bool(field string, val any) {
+	if _, ok := val.(bool); !ok {
+		v.fail(field, "got %T, want Boolean", val)
+	}
+}
+
+// time checks for a timestamp in one of the formats that [TimeFormat]
+// describes: an RFC 3339 string, or an integer number of nanoseconds since the
+// Unix epoch.
+func (v *validator) time(field string, val any) {
+	switch val := val.(type) {
+	case string:
+		// This also accepts TimeFormatRFC3339Millis.
+		if _, err := time.Parse(time.RFC3339Nano, val); err != nil {
+			v.fail(field, "invalid timestamp: %s", err)
+		}
+	case json.Number:
+		if _, err := strconv.ParseInt(val.String(), 10, 64); err != nil {
+			v.fail(field, "invalid timestamp %s", val)
+		}
+	default:
+		v.fail(field, "got %T, want string or number", val)
+	}
+}
+
+// int64 checks for a 64-bit integer.  The protocol buffer JSON mapping allows
+// both numbers and strings for them.
+func (v *validator) int64(field string, val any) {
+	var s string
+	switch val := val.(type) {
+	case json.Number:
+		s = val.String()
+	case string:
+		s = val
+	default:
+		v.fail(field, "got %T, want integer", val)
+		return
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+		v.fail(field, "invalid integer %q", s)
+	}
+}
+
+// int32 checks for a 32-bit integer, which must be a JSON number.
+func (v *validator) int32(field string, val any) {
+	n, ok := val.(json.Number)
+	if !ok {
+		v.fail(field, "got %T, want number", val)
+		return
+	}
+	if _, err := strconv.ParseInt(n.String(), 10, 32); err != nil {
+		v.fail(field, "invalid integer %s", n)
+	}
+}
+
+// duration checks for a duration in the protocol buffer JSON format, e.g.
+// “1.5s”.
+func (v *validator) duration(field string, val any) {
+	s, ok := val.(string)
+	if !ok {
+		v.fail(field, "got %T, want string", val)
+		return
+	}
+	if !durationPattern.MatchString(s) {
+		v.fail(field, "invalid duration %q", s)
+	}
+}
+
+var durationPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]{1,9})?s$`)
+
+type fieldValidator func(v *validator, field string, val any)
+
+func (v *validator) object(field string, val any, fields map[string]fieldValidator) {
+	m, ok := val.(map[string]any)
+	if !ok {
+		v.fail(field, "got %T, want object", val)
+		return
+	}
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		val := m[key]
+		f, ok := fields[key]
+		if !ok {
+			v.fail(field+"."+key, "unknown field")
+			continue
+		}
+		f(v, field+"."+key, val)
+	}
+}
+
+// Limits for labels, see
+// https://cloud.google.com/logging/quotas#log-limits.
+const (
+	maxLabelKeyBytes   = 512
+	maxLabelValueBytes = 64 << 10
+)
+
+func (v *validator) labels(field string, val any) {
+	m, ok := val.(map[string]any)
+	if !ok {
+		v.fail(field, "got %T, want object", val)
+		return
+	}
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		val := m[key]
+		if len(key) > maxLabelKeyBytes {
+			v.fail(field, "label key %.20q… is longer than %d bytes", key, maxLabelKeyBytes)
+		}
+		s, ok := val.(string)
+		if !ok {
+			v.fail(field+"."+key, "got %T, want string", val)
+			continue
+		}
+		if len(s) > maxLabelValueBytes {
+			v.fail(field+"."+key, "label value is longer than %d bytes", maxLabelValueBytes)
+		}
+	}
+}
+
+var validSeverities = map[string]bool{
+	"DEFAULT":   true,
+	"DEBUG":     true,
+	"INFO":      true,
+	"NOTICE":    true,
+	"WARNING":   true,
+	"ERROR":     true,
+	"CRITICAL":  true,
+	"ALERT":     true,
+	"EMERGENCY": true,
+}
+
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+var httpRequestFields = map[string]fieldValidator{
+	"requestMethod":                  (*validator).string,
+	"requestUrl":                     (*validator).string,
+	"requestSize":                    (*validator).int64,
+	"status":                         (*validator).int32,
+	"responseSize":                   (*validator).int64,
+	"userAgent":                      (*validator).string,
+	"remoteIp":                       (*validator).string,
+	"serverIp":                       (*validator).string,
+	"referer":                        (*validator).string,
+	"latency":                        (*validator).duration,
+	"cacheLookup":                    (*validator).bool,
+	"cacheHit":                       (*validator).bool,
+	"cacheValidatedWithOriginServer": (*validator).bool,
+	"cacheFillBytes":                 (*validator).int64,
+	"protocol":                       (*validator).string,
+}
+
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntrySourceLocation
+var sourceLocationFields = map[string]fieldValidator{
+	"file":     (*validator).string,
+	"line":     (*validator).int64,
+	"function": (*validator).string,
+}
+
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntryOperation
+var operationFields = map[string]fieldValidator{
+	"id":       (*validator).string,
+	"producer": (*validator).string,
+	"first":    (*validator).bool,
+	"last":     (*validator).bool,
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestValidate(t *testing.T) {
+	input := strings.Join([]string{
+		`{"severity":"INFO","message":"ok","time":"2024-01-01T00:00:00Z","httpRequest":{"status":200,"latency":"1.5s","responseSize":"123"}}`,
+		`{"severity":"VERBOSE","message":123}`,
+		`{"httpRequest":{"status":"200","latency":"1500ms","requestSize":1.5,"foo":"bar"}}`,
+		`{"logging.googleapis.com/sourceLocation":{"line":"abc"},"logging.googleapis.com/labels":{"a":1,"b":"c"}}`,
+		`{"logging.googleapis.com/trace_sampled":"true","time":"yesterday"}`,
+		`not JSON`,
+		`{"time":"2024-01-01T00:00:00.000Z"}`,
+		`{"time":1704067200000000000}`,
+		`{"time":1.5}`,
+	}, "\n")
+	want := []aelog.Violation{
+		{1, "message", `got json.Number, want string`},
+		{1, "severity", `invalid severity VERBOSE`},
+		{2, "httpRequest.foo", `unknown field`},
+		{2, "httpRequest.latency", `invalid duration "1500ms"`},
+		{2, "httpRequest.requestSize", `invalid integer "1.5"`},
+		{2, "httpRequest.status", `got string, want number`},
+		{3, "logging.googleapis.com/labels.a", `got json.Number, want string`},
+		{3, "logging.googleapis.com/sourceLocation.line", `invalid integer "abc"`},
+		{4, "logging.googleapis.com/trace_sampled", `got string, want Boolean`},
+		{4, "time", `invalid timestamp: parsing time "yesterday" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "yesterday" as "2006"`},
+		{5, "", `invalid JSON object: invalid character 'o' in literal null (expecting 'u')`},
+		{8, "time", `invalid timestamp 1.5`},
+	}
+	// The order of violations is deterministic.
+	for range 10 {
+		got, err := aelog.Validate(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Error("-got +want", diff)
+			break
+		}
+	}
+}
+
+func TestValidate_handler(t *testing.T) {
+	for _, format := range []aelog.TimeFormat{aelog.TimeFormatRFC3339Nano, aelog.TimeFormatRFC3339Millis, aelog.TimeFormatUnixNano} {
+		testValidateHandler(t, format)
+	}
+}
+
+func testValidateHandler(t *testing.T, format aelog.TimeFormat) {
+	// Our own output should always be valid.
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{AddSource: true, Level: aelog.LevelDebug}, &aelog.Options{ProjectID: "test", TimeFormat: format}))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		for l := aelog.LevelDebug; l <= aelog.LevelEmergency+1; l++ {
+			log.Log(r.Context(), l, "message", "attr", 123)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "abc/123;o=1")
+	req.Header.Set("Referer", "https://example.com/")
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	got, err := aelog.Validate(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) > 0 {
+		t.Errorf("time format %d: %v", format, got)
+	}
+}
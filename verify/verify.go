@@ -0,0 +1,190 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verify checks end to end that log records written by an
+// [aelog.Handler] arrive in Cloud Logging with the expected structure.  It’s
+// meant for integration tests that run in a real Google Cloud environment,
+// where it catches format regressions that unit tests can’t see.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/phst/aelog"
+)
+
+// Verifier emits marker records and checks that Cloud Logging ingested them
+// correctly.  The zero Verifier isn’t valid; at least Client, ProjectID, and
+// Logger must be set.
+type Verifier struct {
+	// HTTP client for the Cloud Logging API.  It must be authorized to
+	// read log entries, for example using the
+	// https://www.googleapis.com/auth/logging.read scope.
+	Client *http.Client
+
+	// Project to which the log entries are written.
+	ProjectID string
+
+	// Logger that writes to the ingestion pipeline, typically a logger
+	// based on an [aelog.Handler] that writes to standard error.
+	Logger *slog.Logger
+
+	// Base URL of the Cloud Logging API.  If empty, use
+	// https://logging.googleapis.com.
+	Endpoint string
+
+	// Interval between two queries for the marker entry.  If zero, use
+	// five seconds.
+	PollInterval time.Duration
+}
+
+// MarkerKey is the key of the attribute that identifies marker records.
+const MarkerKey = "aelogVerifyMarker"
+
+// Verify logs a marker record at [aelog.LevelWarn] with trace and HTTP
+// request information, and then queries the Cloud Logging API until the
+// corresponding log entry appears or the context is done.  Ingestion
+// typically takes a few seconds, so pass a context with a generous deadline.
+// Verify returns an error if the entry didn’t appear or if its severity,
+// trace, span, sampling, or HTTP request fields don’t match.
+func (v *Verifier) Verify(ctx context.Context) error {
+	marker := randomHex(16)
+	trace := randomHex(16)
+	const span = "1234"
+	const url = "/aelog-verify"
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		v.Logger.WarnContext(r.Context(), "aelog verification", MarkerKey, marker)
+	}
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req.Header.Set("X-Cloud-Trace-Context", trace+"/"+span+";o=1")
+	aelog.Middleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	interval := v.PollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		e, err := v.find(ctx, marker)
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			return v.check(e, trace, span, url)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("verify: marker entry %s not found: %w", marker, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+// entry contains the fields of a LogEntry that Verify checks.  See
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry.
+type entry struct {
+	Severity     string `json:"severity"`
+	Trace        string `json:"trace"`
+	SpanID       string `json:"spanId"`
+	TraceSampled bool   `json:"traceSampled"`
+	HTTPRequest  *struct {
+		RequestMethod string `json:"requestMethod"`
+		RequestURL    string `json:"requestUrl"`
+	} `json:"httpRequest"`
+}
+
+// find returns the marker entry, or nil if it hasn’t been ingested yet.
+func (v *Verifier) find(ctx context.Context, marker string) (*entry, error) {
+	endpoint := v.Endpoint
+	if endpoint == "" {
+		endpoint = "https://logging.googleapis.com"
+	}
+	// https://cloud.google.com/logging/docs/reference/v2/rest/v2/entries/list
+	body, err := json.Marshal(map[string]any{
+		"resourceNames": []string{"projects/" + v.ProjectID},
+		"filter":        fmt.Sprintf("jsonPayload.%s=%q", MarkerKey, marker),
+		"pageSize":      1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v2/entries:list", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("verify: listing log entries failed with status %s: %s", resp.Status, b)
+	}
+	var r struct {
+		Entries []*entry `json:"entries"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("verify: invalid response from Cloud Logging: %w", err)
+	}
+	if len(r.Entries) == 0 {
+		return nil, nil
+	}
+	return r.Entries[0], nil
+}
+
+func (v *Verifier) check(e *entry, trace, span, url string) error {
+	var errs []error
+	mismatch := func(field, got, want string) {
+		if got != want {
+			errs = append(errs, fmt.Errorf("verify: %s is %q, want %q", field, got, want))
+		}
+	}
+	mismatch("severity", e.Severity, "WARNING")
+	mismatch("trace", e.Trace, fmt.Sprintf("projects/%s/traces/%s", v.ProjectID, trace))
+	mismatch("span ID", e.SpanID, span)
+	if !e.TraceSampled {
+		errs = append(errs, errors.New("verify: entry isn’t marked as sampled"))
+	}
+	if r := e.HTTPRequest; r == nil {
+		errs = append(errs, errors.New("verify: entry has no HTTP request"))
+	} else {
+		mismatch("request method", r.RequestMethod, http.MethodGet)
+		mismatch("request URL", r.RequestURL, url)
+	}
+	return errors.Join(errs...)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phst/aelog"
+	"github.com/phst/aelog/verify"
+)
+
+func TestVerifier(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		severity string // override for the ingested severity
+		wantErr  string
+	}{
+		{"ok", "", ""},
+		{"wrong severity", "INFO", "severity"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			buf := new(bytes.Buffer)
+			log := slog.New(aelog.NewHandler(lockedWriter{&mu, buf}, nil, &aelog.Options{ProjectID: "test"}))
+			srv := httptest.NewServer(ingest(t, &mu, buf, tc.severity))
+			defer srv.Close()
+
+			v := &verify.Verifier{
+				Client:       srv.Client(),
+				ProjectID:    "test",
+				Logger:       log,
+				Endpoint:     srv.URL,
+				PollInterval: time.Millisecond,
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := v.Verify(ctx)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Error(err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("got error %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// ingest returns a fake implementation of the entries.list method that
+// converts the records written to buf into log entries, like the logging
+// agent would.
+func ingest(t *testing.T, mu *sync.Mutex, buf *bytes.Buffer, severity string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/entries:list" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Filter string `json:"filter"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		mu.Unlock()
+		var entries []map[string]any
+		for _, line := range lines {
+			var rec map[string]any
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				t.Error(err)
+				continue
+			}
+			marker, _ := rec[verify.MarkerKey].(string)
+			if marker == "" || !strings.Contains(req.Filter, marker) {
+				continue
+			}
+			e := map[string]any{
+				"severity":     rec["severity"],
+				"trace":        rec["logging.googleapis.com/trace"],
+				"spanId":       rec["logging.googleapis.com/spanId"],
+				"traceSampled": rec["logging.googleapis.com/trace_sampled"],
+				"httpRequest":  rec["httpRequest"],
+			}
+			if severity != "" {
+				e["severity"] = severity
+			}
+			entries = append(entries, e)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"entries": entries})
+	})
+}
+
+type lockedWriter struct {
+	mu  *sync.Mutex
+	buf *bytes.Buffer
+}
+
+func (w lockedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(b)
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// NewWriter returns a new [Writer] that logs to l at the given level.  If l is
+// nil, the writer uses the default logger.
+func NewWriter(l *slog.Logger, level slog.Level) *Writer {
+	return &Writer{l: l, level: level}
+}
+
+// Writer is an [io.Writer] that turns line-oriented output into structured log
+// records, one record per line.  This is useful for output that can’t be
+// logged using [slog], for example the output of legacy libraries, C code, or
+// subprocesses:
+//
+//	cmd.Stderr = aelog.NewWriter(log, aelog.LevelInfo)
+//
+// If a line starts with a severity such as “ERROR:”, “WARN:”, or “[DEBUG]”
+// (in any case), the writer removes it and logs the rest of the line at the
+// corresponding level, see [ParseLevel].  It also recognizes “FATAL” as
+// [LevelCritical].  Other lines are logged at the level passed to
+// [NewWriter].  The writer ignores empty lines and splits lines longer than
+// 64 KiB.  Call [Writer.Flush] or [Writer.Close] to log an incomplete last
+// line.  Writer is safe for concurrent use.
+//
+// Use [NewWriter] to create Writer objects.
+type Writer struct {
+	l     *slog.Logger
+	level slog.Level
+
+	mu  sync.Mutex
+	buf []byte // incomplete line
+}
+
+// maxWriterLine is the maximum length of a line that a Writer logs as a
+// single record.
+const maxWriterLine = 64 << 10
+
+// Write implements [io.Writer.Write].  It logs all complete lines in b.  Write
+// never fails.
+func (w *Writer) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	buf := append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 || i > maxWriterLine {
+			if len(buf) < maxWriterLine {
+				break
+			}
+			w.log(buf[:maxWriterLine])
+			buf = buf[maxWriterLine:]
+			continue
+		}
+		w.log(buf[:i])
+		buf = buf[i+1:]
+	}
+	// Move the incomplete line to the start of the buffer.
+	w.buf = append(w.buf[:0], buf...)
+	return len(b), nil
+}
+
+// Flush logs the remaining incomplete line, if any.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.log(w.buf)
+	w.buf = nil
+	return nil
+}
+
+// Close is the same as [Writer.Flush].  It doesn’t close the underlying
+// logger.
+func (w *Writer) Close() error {
+	return w.Flush()
+}
+
+// log logs a single line.  w.mu must be locked.
+func (w *Writer) log(line []byte) {
+	s := strings.TrimRight(string(line), "\r\n")
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+	level := w.level
+	if l, rest, ok := sniffLevel(s); ok {
+		level, s = l, rest
+	}
+	logPC(context.Background(), w.l, level, 0, s)
+}
+
+// sniffLevel detects a severity prefix such as “ERROR:” or “[WARN]” at the
+// start of s.  If found, it returns the corresponding level and the rest of
+// s.
+func sniffLevel(s string) (slog.Level, string, bool) {
+	t := strings.TrimLeft(s, " \t")
+	var word, rest string
+	if r, ok := strings.CutPrefix(t, "["); ok {
+		var found bool
+		if word, rest, found = strings.Cut(r, "]"); !found {
+			return 0, s, false
+		}
+		rest = strings.TrimPrefix(rest, ":")
+	} else {
+		var found bool
+		if word, rest, found = strings.Cut(t, ":"); !found {
+			return 0, s, false
+		}
+	}
+	if word == "" || strings.ContainsAny(word, " \t") {
+		return 0, s, false
+	}
+	var level slog.Level
+	switch strings.ToUpper(word) {
+	case "DEBUG", "INFO", "NOTICE", "WARN", "WARNING", "ERROR", "CRITICAL", "ALERT", "EMERGENCY":
+		// Can’t fail, since all of these are valid level names.
+		level, _ = ParseLevel(word)
+	case "FATAL":
+		level = LevelCritical
+	default:
+		return 0, s, false
+	}
+	return level, strings.TrimLeft(rest, " \t"), true
+}
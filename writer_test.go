@@ -0,0 +1,74 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aelog_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phst/aelog"
+)
+
+func TestWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := slog.New(aelog.NewHandler(buf, &slog.HandlerOptions{Level: aelog.LevelDebug}, nil))
+	w := aelog.NewWriter(log.With("component", "legacy"), aelog.LevelInfo)
+	fmt.Fprint(w, "first line\nERROR: something ")
+	fmt.Fprint(w, "failed\r\n\n  [debug] details\nwarning:x\nFatal: oops\nerror in: parsing\nincomplete")
+	if err := w.Close(); err != nil {
+		t.Error(err)
+	}
+
+	got := parseRecords(t, buf)
+	want := []map[string]any{
+		{"severity": "INFO", "message": "first line", "component": "legacy"},
+		{"severity": "ERROR", "message": "something failed", "component": "legacy"},
+		{"severity": "DEBUG", "message": "details", "component": "legacy"},
+		{"severity": "WARNING", "message": "x", "component": "legacy"},
+		{"severity": "CRITICAL", "message": "oops", "component": "legacy"},
+		{"severity": "INFO", "message": "error in: parsing", "component": "legacy"},
+		{"severity": "INFO", "message": "incomplete", "component": "legacy"},
+	}
+	if diff := cmp.Diff(got, want, ignoreTime); diff != "" {
+		t.Error("-got +want", diff)
+	}
+}
+
+func TestWriter_longLine(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := aelog.NewWriter(slog.New(aelog.NewHandler(buf, nil, nil)), aelog.LevelInfo)
+	line := strings.Repeat("x", 100<<10)
+	fmt.Fprintln(w, line)
+
+	entries, err := aelog.DecodeEntries(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, e := range entries {
+		msgs = append(msgs, e.Message)
+	}
+	if got := strings.Join(msgs, ""); got != line {
+		t.Errorf("got %d records with %d bytes in total, want %d bytes", len(msgs), len(got), len(line))
+	}
+	if len(msgs) != 2 {
+		t.Errorf("got %d records, want two", len(msgs))
+	}
+}